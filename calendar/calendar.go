@@ -4,143 +4,683 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/leksusdev/calendarOfEvents/config"
 	"github.com/leksusdev/calendarOfEvents/datetime"
 	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+	"github.com/leksusdev/calendarOfEvents/reminder"
 	"github.com/leksusdev/calendarOfEvents/storage"
 )
 
 type Calendar struct {
+	mu             sync.RWMutex
+	saveMu         sync.Mutex
 	calendarEvents map[string]*events.Event
+	trash          map[string]*events.Event
 	storage        storage.Store
-	Notification   chan string
+	records        storage.RecordStore
+	repo           storage.Repository
+	saved          map[string][]byte
+
+	calendars      map[string]*events.Calendar
+	activeCalendar string
+
+	journal         *storage.Journal
+	history         []JournalEntry
+	undoStack       []int64
+	redoStack       []int64
+	pending         map[string]json.RawMessage
+	missedReminders []string
+	loadWarnings    []string
+	remindersOff    bool
+	strictConflicts bool
+
+	autosaveMu    sync.Mutex
+	autosaveTimer *time.Timer
+
+	Notification chan string
+	notifyMu     sync.RWMutex
+	notifyDone   chan struct{}
+	closeOnce    sync.Once
+	closed       bool
 }
 
 var (
 	ErrEventNotFound    = errors.New("событие не найдено")
-	ErrReminderNotFound = errors.New("у события нет напоминания")
+	ErrReminderNotFound = errors.New("напоминание не найдено")
+	ErrAmbiguousID      = errors.New("префикс ID подходит к нескольким событиям")
 )
 
+// NewCalendar создаёт календарь поверх хранилища. Календарь работает с событиями
+// через storage.Repository и сохраняет только изменения; хранилище, которое умеет
+// сохранять данные лишь целиком, оборачивается в storage.BlobRecords.
 func NewCalendar(s storage.Store) *Calendar {
+	rs, ok := s.(storage.RecordStore)
+	if !ok {
+		rs = storage.NewBlobRecords(s)
+	}
 	return &Calendar{
-		calendarEvents: make(map[string]*events.Event),
-		storage:        s,
-		Notification:   make(chan string),
+		calendarEvents:  make(map[string]*events.Event),
+		trash:           make(map[string]*events.Event),
+		calendars:       make(map[string]*events.Calendar),
+		activeCalendar:  events.DefaultCalendar,
+		storage:         s,
+		records:         rs,
+		repo:            storage.NewRepository(rs),
+		Notification:    make(chan string),
+		notifyDone:      make(chan struct{}),
+		strictConflicts: config.StrictConflicts,
 	}
 }
 
+// Save сохраняет изменения, накопленные с прошлого сохранения.
 func (c *Calendar) Save() error {
-	var data []byte
-	var err error
-	if config.PrettyJSON {
-		data, err = json.MarshalIndent(c.calendarEvents, "", "  ")
-	} else {
-		data, err = json.Marshal(c.calendarEvents)
+	c.cancelAutosave()
+
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	return c.saveRecords()
+}
+
+// Rewrite сохраняет изменения и переписывает данные хранилища целиком, например
+// чтобы зашифровать их новым паролем.
+func (c *Calendar) Rewrite() error {
+	if err := c.Save(); err != nil {
+		return err
 	}
-	if err != nil {
-		return fmt.Errorf("ошибка сериализации JSON: %w", err)
+	rw, ok := c.records.(storage.Rewriter)
+	if !ok {
+		return nil
+	}
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	return rw.Rewrite()
+}
+
+// marshalEvents сериализует события и корзину по ID. Вызывающий должен держать
+// блокировку календаря.
+func (c *Calendar) marshalEvents() (map[string][]byte, error) {
+	out := make(map[string][]byte, len(c.calendarEvents)+len(c.trash))
+	for _, m := range []map[string]*events.Event{c.calendarEvents, c.trash} {
+		for id, e := range m {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка сериализации JSON: %w", err)
+			}
+			out[id] = data
+		}
 	}
-	return c.storage.Save(data)
+	return out, nil
 }
 
+// ChangePassphrase перешифровывает новым паролем данные зашифрованного хранилища и
+// их копии, в том числе резервную копию, которая остаётся от прежнего файла.
+func (c *Calendar) ChangePassphrase(current, next string) error {
+	r, ok := storage.Base(c.storage).(storage.Rekeyer)
+	if !ok {
+		return storage.ErrNotEncrypted
+	}
+	if !r.CheckPassphrase(current) {
+		return storage.ErrWrongPassphrase
+	}
+	if err := r.SetPassphrase(next); err != nil {
+		return err
+	}
+	if err := c.Rewrite(); err != nil {
+		return err
+	}
+	if _, err := r.RekeyCopies(current); err != nil {
+		return fmt.Errorf("ошибка перешифровки резервных копий: %w", err)
+	}
+	return nil
+}
+
+// Load читает события из хранилища. Предупреждения хранилища (восстановление из
+// резервной копии, миграция формата) доступны через LoadWarnings.
 func (c *Calendar) Load() error {
-	data, err := c.storage.Load()
+	if o, ok := c.records.(storage.Opener); ok {
+		warnings, err := o.Open()
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.loadWarnings = append(c.loadWarnings, warnings...)
+		c.mu.Unlock()
+	}
+	if err := c.loadRecords(); err != nil {
+		return err
+	}
+	return c.loadCalendars()
+}
+
+func (c *Calendar) LoadWarnings() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	warnings := make([]string, len(c.loadWarnings))
+	copy(warnings, c.loadWarnings)
+	return warnings
+}
+
+// mutate выполняет изменение под блокировкой календаря, после успешного изменения
+// записывает в журнал события, отмеченные track, и запускает автосохранение.
+func (c *Calendar) mutate(op Op, fn func() error) error {
+	c.mu.Lock()
+	err := fn()
+	if err != nil {
+		c.pending = nil
+	} else if op != OpUndo && op != OpRedo {
+		c.commitJournal(op, 0)
+	}
+	c.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("ошибка загрузки из стораджа: %w", err)
+		return err
 	}
+	c.changed()
+	return nil
+}
 
-	if len(data) == 0 {
-		return nil
+func (c *Calendar) changed() {
+	if !config.Autosave {
+		return
+	}
+	if config.AutosaveDelay <= 0 {
+		c.autosave()
+		return
 	}
 
-	if err := json.Unmarshal(data, &c.calendarEvents); err != nil {
-		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	c.autosaveMu.Lock()
+	defer c.autosaveMu.Unlock()
+	if c.autosaveTimer != nil {
+		c.autosaveTimer.Stop()
+	}
+	c.autosaveTimer = time.AfterFunc(config.AutosaveDelay, c.autosave)
+}
+
+func (c *Calendar) autosave() {
+	if err := c.Save(); err != nil {
+		logger.Error(fmt.Sprintf("Ошибка автосохранения: %v", err))
 	}
-	return nil
 }
 
-func (c *Calendar) AddEvent(title string, dateStr string, priority events.Priority) (*events.Event, error) {
-	e, err := events.NewEvent(title, dateStr, priority)
+func (c *Calendar) cancelAutosave() {
+	c.autosaveMu.Lock()
+	defer c.autosaveMu.Unlock()
+	if c.autosaveTimer != nil {
+		c.autosaveTimer.Stop()
+		c.autosaveTimer = nil
+	}
+}
+
+// rearmReminders запускает напоминания загруженных событий и сообщает, были ли
+// пропущенные: их отметки Sent изменились, и календарь нужно сохранить.
+func (c *Calendar) rearmReminders() bool {
+	now := time.Now()
+	n := len(c.missedReminders)
+	for _, e := range c.calendarEvents {
+		c.armReminders(e, now)
+	}
+	return len(c.missedReminders) > n
+}
+
+// armReminders запускает напоминания события, которое появилось в календаре (загрузка,
+// импорт, восстановление) или перенесено на другое время. Пропущенные напоминания в режиме summary добавляются в
+// MissedReminders. Вызывающий должен держать блокировку календаря.
+func (c *Calendar) armReminders(e *events.Event, now time.Time) {
+	c.bindReminders(e)
+	if c.remindersOff {
+		return
+	}
+	for _, r := range e.Reminders {
+		state := r.Snapshot()
+		if state.Sent {
+			continue
+		}
+
+		if !r.Missed(now) {
+			r.Start()
+			logger.Info(fmt.Sprintf("Напоминание восстановлено для события ID=%s: ReminderID=%s", e.ID, r.ID))
+			continue
+		}
+
+		switch config.MissedRemindersPolicy {
+		case config.MissedRemindersFire:
+			r.StartMissed()
+		default:
+			c.missedReminders = append(c.missedReminders,
+				fmt.Sprintf("\"%s\" - \"%s\" (событие \"%s\")", state.Message, datetime.FormatLocal(state.At), e.Title))
+			r.Skip()
+		}
+		logger.Info(fmt.Sprintf("Пропущено напоминание для события ID=%s: ReminderID=%s", e.ID, r.ID))
+	}
+}
+
+// bindReminders привязывает напоминания к уведомлениям календаря. Следующее срабатывание
+// повторяющегося события вычисляется по копии события, поэтому таймерам не нужна
+// блокировка календаря; после каждого изменения события привязку нужно обновить.
+func (c *Calendar) bindReminders(e *events.Event) {
+	var next reminder.NextFunc
+	if e.IsRecurring() {
+		next = e.Clone().NextReminderAt
+	}
+	for _, r := range e.Reminders {
+		r.Bind(c.Notify, next, c.reminderFired)
+	}
+}
+
+// DisableReminders отключает запуск сохранённых напоминаний при загрузке и импорте,
+// чтобы короткий неинтерактивный запуск не помечал их как пропущенные.
+func (c *Calendar) DisableReminders() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remindersOff = true
+}
+
+// MissedReminders возвращает пропущенные напоминания в порядке обнаружения: при
+// загрузке, импорте, восстановлении из корзины, отмене изменений и переносе события.
+func (c *Calendar) MissedReminders() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	missed := make([]string, len(c.missedReminders))
+	copy(missed, c.missedReminders)
+	return missed
+}
+
+func (c *Calendar) AddEvent(title string, dateStr string, endStr string, priority events.Priority) (*events.Event, error) {
+	return c.AddEventTo("", title, dateStr, endStr, priority)
+}
+
+// AddEventTo добавляет событие в календарь calendarName (пустое имя — текущий
+// календарь); без приоритета событие получает приоритет календаря по умолчанию.
+func (c *Calendar) AddEventTo(calendarName string, title string, dateStr string, endStr string, priority events.Priority) (*events.Event, error) {
+	cal, err := c.GetCalendar(calendarName)
+	if err != nil {
+		return nil, err
+	}
+	if priority == "" {
+		priority = cal.Priority
+	}
+	e, err := events.NewEvent(title, dateStr, endStr, priority)
 	if err != nil {
 		return nil, err
 	}
+	e.Calendar = eventCalendar(cal.Name)
 
-	c.calendarEvents[e.ID] = e
-	return e, nil
+	var clone *events.Event
+	err = c.mutate(OpAdd, func() error {
+		if c.strictConflicts {
+			if list := c.conflictsFor(e); len(list) > 0 {
+				return &ConflictError{Conflicts: list}
+			}
+		}
+		c.track(e.ID)
+		c.calendarEvents[e.ID] = e
+		clone = e.Clone()
+		return nil
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Событие не добавлено: ID=%s: %v", e.ID, err))
+		return nil, err
+	}
+	return clone, nil
 }
 
 func (c *Calendar) GetEvents() []*events.Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	eventsList := make([]*events.Event, 0, len(c.calendarEvents))
 	for _, e := range c.calendarEvents {
-		eventsList = append(eventsList, e)
+		eventsList = append(eventsList, e.Clone())
 	}
+	sort.Slice(eventsList, func(i, j int) bool {
+		if !eventsList[i].StartAt.Equal(eventsList[j].StartAt) {
+			return eventsList[i].StartAt.Before(eventsList[j].StartAt)
+		}
+		return eventsList[i].ID < eventsList[j].ID
+	})
 	return eventsList
 }
 
-func (c *Calendar) DeleteEvent(id string) (*events.Event, error) {
-	e, exists := c.calendarEvents[id]
-	if !exists {
+// lookup находит событие по ID или однозначному префиксу ID, как git для коммитов.
+// Вызывающий должен держать блокировку календаря.
+func (c *Calendar) lookup(id string) (*events.Event, error) {
+	return lookupIn(c.calendarEvents, id)
+}
+
+func lookupIn(m map[string]*events.Event, id string) (*events.Event, error) {
+	id = strings.TrimSpace(id)
+	if e, exists := m[id]; exists {
+		return e, nil
+	}
+	if id == "" {
 		return nil, fmt.Errorf("id=%q: %w", id, ErrEventNotFound)
 	}
-	if e.Reminder != nil {
-		e.RemoveReminder()
+
+	switch list := candidatesIn(m, id); len(list) {
+	case 0:
+		return nil, fmt.Errorf("id=%q: %w", id, ErrEventNotFound)
+	case 1:
+		return m[list[0].ID], nil
+	default:
+		return nil, &AmbiguousIDError{Prefix: id, Candidates: list}
 	}
-	delete(c.calendarEvents, id)
-	return e, nil
 }
 
-func (c *Calendar) EditEvent(id string, title string, dateStr string, priority events.Priority) (string, string, error) {
-	e, exists := c.calendarEvents[id]
-	if !exists {
-		return "", "", fmt.Errorf("id=%q: %w", id, ErrEventNotFound)
+func (c *Calendar) GetEvent(id string) (*events.Event, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(id)
+	if err != nil {
+		return nil, err
 	}
+	return e.Clone(), nil
+}
 
-	oldTitle := e.Title
+func (c *Calendar) Occurrences(from, to time.Time) []events.Occurrence {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	if title == "_" {
-		title = e.Title
+	var list []events.Occurrence
+	for _, e := range c.calendarEvents {
+		list = append(list, e.Clone().Occurrences(from, to)...)
 	}
-	if dateStr == "_" {
-		dateStr = datetime.FormatLocal(e.StartAt)
+	sortOccurrences(list)
+	return list
+}
+
+// ListOccurrences возвращает все разовые события и вхождения повторяющихся событий в [from, to),
+// а если в интервал не попало ни одно вхождение — ближайшее следующее.
+func (c *Calendar) ListOccurrences(from, to time.Time) []events.Occurrence {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var list []events.Occurrence
+	for _, e := range c.calendarEvents {
+		list = append(list, eventOccurrences(e.Clone(), from, to, true)...)
 	}
-	if priority == "_" {
-		priority = e.Priority
+	sortOccurrences(list)
+	return list
+}
+
+func sortOccurrences(list []events.Occurrence) {
+	sortQuery(list, SortByStart, false)
+}
+
+// ImportEvents добавляет события из файла или заменяет события с теми же ID. Поле
+// Calendar импортируемого события — имя календаря из файла (events.DefaultCalendar для
+// календаря по умолчанию); если оно пустое, обновлённое событие остаётся в своём
+// календаре, а новое попадает в текущий.
+func (c *Calendar) ImportEvents(list []*events.Event) (int, int) {
+	added, updated := 0, 0
+	_ = c.mutate(OpImport, func() error {
+		now := time.Now()
+		for _, e := range list {
+			c.track(e.ID)
+			delete(c.trash, e.ID)
+			old, exists := c.calendarEvents[e.ID]
+			switch {
+			case e.Calendar != "":
+				e.Calendar = eventCalendar(e.Calendar)
+			case exists:
+				e.Calendar = old.Calendar
+			default:
+				e.Calendar = eventCalendar(c.activeCalendar)
+			}
+			if exists {
+				old.RemoveReminders()
+				updated++
+				logger.Info(fmt.Sprintf("Импортом обновлено событие: ID=%s, Title=%s", e.ID, e.Title))
+			} else {
+				added++
+				logger.Info(fmt.Sprintf("Импортировано событие: ID=%s, Title=%s", e.ID, e.Title))
+			}
+			c.calendarEvents[e.ID] = e
+			c.armReminders(e, now)
+		}
+		return nil
+	})
+	return added, updated
+}
+
+func (c *Calendar) DeleteEvent(id string) (*events.Event, error) {
+	var removed *events.Event
+	err := c.mutate(OpDelete, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
+		c.moveToTrash(e, time.Now())
+		removed = e.Clone()
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return removed, nil
+}
+
+func (c *Calendar) EditEvent(id string, title string, dateStr string, endStr string, priority events.Priority) (string, string, error) {
+	var oldTitle, newTitle string
+	err := c.mutate(OpUpdate, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
 
-	err := e.Update(title, dateStr, priority)
+		oldTitle = e.Title
+
+		if title == "_" {
+			title = e.Title
+		}
+		if dateStr == "_" {
+			if e.AllDay {
+				dateStr = datetime.FormatLocalDate(e.StartAt)
+			} else {
+				dateStr = datetime.FormatLocal(e.StartAt)
+			}
+		}
+		switch endStr {
+		case "_":
+			endStr = ""
+			if !e.EndAt.IsZero() {
+				endStr = e.Duration().String()
+			}
+		case "-":
+			endStr = ""
+		}
+		if priority == "_" {
+			priority = e.Priority
+		}
+
+		if c.strictConflicts {
+			if updated, err := e.Updated(title, dateStr, endStr, priority); err == nil {
+				if list := c.conflictsFor(updated); len(list) > 0 {
+					return &ConflictError{Conflicts: list}
+				}
+			}
+		}
+		if err := e.Update(title, dateStr, endStr, priority); err != nil {
+			return err
+		}
+		// Напоминание, перенесённое вместе с событием на прошедшее время, уже не
+		// сработает само и сообщается как пропущенное.
+		c.armReminders(e, time.Now())
+		newTitle = e.Title
+		return nil
+	})
 	if err != nil {
 		return "", "", err
 	}
-	return oldTitle, e.Title, nil
+	return oldTitle, newTitle, nil
+}
+
+// reminderFired сохраняет календарь после срабатывания напоминания. Отметка Sent и
+// перенос на следующее повторение меняются в обход mutate, поэтому без сохранения
+// после аварийного завершения напоминание снова показалось бы пропущенным.
+func (c *Calendar) reminderFired() {
+	c.notifyMu.RLock()
+	closed := c.closed
+	c.notifyMu.RUnlock()
+	if !closed {
+		c.changed()
+	}
 }
 
 func (c *Calendar) Notify(msg string) {
-	c.Notification <- msg
+	c.notifyMu.RLock()
+	defer c.notifyMu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.Notification <- msg:
+	case <-c.notifyDone:
+	}
 }
 
 func (c *Calendar) Close() {
-	close(c.Notification)
+	c.closeOnce.Do(func() {
+		close(c.notifyDone)
+		c.notifyMu.Lock()
+		c.closed = true
+		close(c.Notification)
+		c.notifyMu.Unlock()
+	})
 }
 
-func (c *Calendar) SetEventReminder(id string, message string, at string) error {
-	e, exists := c.calendarEvents[id]
-	if !exists {
-		return fmt.Errorf("id=%q: %w", id, ErrEventNotFound)
+func (c *Calendar) SetEventReminder(id string, message string, at string) (*reminder.Reminder, error) {
+	var snapshot *reminder.Reminder
+	err := c.mutate(OpRemind, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
+		r, err := e.AddReminder(message, at, c.Notify)
+		if err != nil {
+			return err
+		}
+		c.bindReminders(e)
+		snapshot = r.Snapshot()
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return e.AddReminder(message, at, c.Notify)
+	return snapshot, nil
 }
 
-func (c *Calendar) CancelEventReminder(id string) error {
-	e, exists := c.calendarEvents[id]
-	if !exists {
-		return fmt.Errorf("id=%q: %w", id, ErrEventNotFound)
+func (c *Calendar) GetEventReminders(id string) ([]*reminder.Reminder, error) {
+	e, err := c.GetEvent(id)
+	if err != nil {
+		return nil, err
 	}
+	return e.Reminders, nil
+}
+
+func (c *Calendar) CancelEventReminder(id string, reminderID string) error {
+	return c.mutate(OpRemindCancel, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
 
-	if e.Reminder == nil {
-		return ErrReminderNotFound
+		if len(e.Reminders) == 0 {
+			return ErrReminderNotFound
+		}
+
+		if reminderID == "" {
+			e.RemoveReminders()
+			return nil
+		}
+		if !e.RemoveReminder(reminderID) {
+			return fmt.Errorf("reminderID=%q: %w", reminderID, ErrReminderNotFound)
+		}
+		return nil
+	})
+}
+
+func (c *Calendar) StopReminders() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, e := range c.calendarEvents {
+		for _, r := range e.Reminders {
+			r.Stop()
+		}
 	}
+}
 
-	e.RemoveReminder()
-	return nil
+func (c *Calendar) SetEventRecurrence(id string, rule string) error {
+	return c.mutate(OpRepeat, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
+		if err := e.SetRecurrence(rule); err != nil {
+			logger.Error(fmt.Sprintf("Ошибка установки повторения для события ID=%s: %v", e.ID, err))
+			return err
+		}
+		c.bindReminders(e)
+		logger.Info(fmt.Sprintf("Установлено повторение для события ID=%s: %s", e.ID, e.Recurrence))
+		return nil
+	})
+}
+
+func (c *Calendar) CancelEventRecurrence(id string) error {
+	return c.mutate(OpRepeatCancel, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
+		if err := e.RemoveRecurrence(); err != nil {
+			return err
+		}
+		c.bindReminders(e)
+		logger.Info(fmt.Sprintf("Повторение отменено для события ID=%s", e.ID))
+		return nil
+	})
+}
+
+func (c *Calendar) SkipOccurrence(id string, at string) error {
+	return c.mutate(OpRepeatSkip, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
+		if err := e.SkipOccurrence(at); err != nil {
+			return err
+		}
+		c.bindReminders(e)
+		return nil
+	})
+}
+
+func (c *Calendar) EditOccurrence(id string, at string, title string, dateStr string) error {
+	return c.mutate(OpRepeatEdit, func() error {
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		c.track(e.ID)
+		if err := e.EditOccurrence(at, title, dateStr); err != nil {
+			return err
+		}
+		c.bindReminders(e)
+		return nil
+	})
 }
@@ -0,0 +1,547 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func newTestCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	c := NewCalendar(storage.NewJsonStorage(filepath.Join(t.TempDir(), "calendar.json")))
+	go func() {
+		for range c.Notification {
+		}
+	}()
+	t.Cleanup(func() {
+		c.StopReminders()
+		c.Close()
+	})
+	return c
+}
+
+func TestConcurrentMutations(t *testing.T) {
+	c := newTestCalendar(t)
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		e, err := c.AddEvent(fmt.Sprintf("Событие %d", i), start, "1h", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Go(func() {
+			for i := 0; i < 50; i++ {
+				id := ids[(w+i)%len(ids)]
+				switch i % 7 {
+				case 0:
+					if _, err := c.AddEvent(fmt.Sprintf("Новое %d %d", w, i), start, "", events.PriorityHigh); err != nil {
+						t.Error(err)
+					}
+				case 1:
+					if _, _, err := c.EditEvent(id, "_", start, "_", events.PriorityMedium); err != nil {
+						t.Error(err)
+					}
+				case 2:
+					if _, err := c.SetEventReminder(id, "Напоминание", "-1h"); err != nil {
+						t.Error(err)
+					}
+				case 3:
+					_ = c.CancelEventReminder(id, "")
+				case 4:
+					for _, e := range c.GetEvents() {
+						e.Title = "Изменённая копия"
+					}
+				case 5:
+					c.Occurrences(time.Now(), time.Now().Add(72*time.Hour))
+				case 6:
+					if err := c.Save(); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, e := range c.GetEvents() {
+		if e.Title == "Изменённая копия" {
+			t.Fatal("GetEvents вернул не копию события")
+		}
+	}
+}
+
+func TestNotifyAfterClose(t *testing.T) {
+	c := NewCalendar(storage.NewJsonStorage(filepath.Join(t.TempDir(), "calendar.json")))
+	c.Close()
+	c.Close()
+	c.Notify("после закрытия")
+}
+
+func TestAutosaveAndRecover(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	c := NewCalendar(storage.NewJsonStorage(filename))
+	t.Cleanup(c.Close)
+
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	first, err := c.AddEvent("Первое", start, "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddEvent("Второе", start, "", events.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filename, []byte(`{"broken`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewCalendar(storage.NewJsonStorage(filename))
+	t.Cleanup(restored.Close)
+	if err := restored.Load(); err != nil {
+		t.Fatalf("Не ожидали ошибку восстановления: %v", err)
+	}
+	if len(restored.LoadWarnings()) != 1 {
+		t.Errorf("Ожидали одно предупреждение, получили: %v", restored.LoadWarnings())
+	}
+	list := restored.GetEvents()
+	if len(list) != 1 || list[0].ID != first.ID {
+		t.Fatalf("Ожидали только событие %s, получили: %v", first.ID, list)
+	}
+
+	matches, _ := filepath.Glob(filename + ".corrupt-*")
+	if len(matches) != 1 {
+		t.Errorf("Ожидали одну копию поврежденного файла, получили: %v", matches)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || !json.Valid(data) {
+		t.Errorf("Ожидали перезаписанный файл данных, получили: %v", err)
+	}
+}
+
+func TestSaveReportsMarshalError(t *testing.T) {
+	db, err := storage.NewSqliteStorage(filepath.Join(t.TempDir(), "calendar.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	stores := map[string]storage.Store{
+		"json":   storage.NewJsonStorage(filepath.Join(t.TempDir(), "calendar.json")),
+		"sqlite": db,
+	}
+	for name, s := range stores {
+		c := NewCalendar(s)
+		c.DisableReminders()
+		t.Cleanup(c.Close)
+		if err := c.Load(); err != nil {
+			t.Fatal(err)
+		}
+		start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+		bad, err := c.AddEvent("Сломанное", start, "", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deleted, err := c.AddEvent("Удаленное", start, "", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.DeleteEvent(deleted.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		// Ошибка сериализации события не должна теряться, когда следом сериализуется корзина.
+		c.mu.Lock()
+		c.calendarEvents[bad.ID].StartAt = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+		c.mu.Unlock()
+		if err := c.Save(); err == nil {
+			t.Errorf("%s: Ожидали ошибку сериализации из Save, получили nil", name)
+		}
+	}
+}
+
+func TestRewriteEncryptsUnchangedData(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	plain := NewCalendar(storage.NewJsonStorage(filename))
+	plain.DisableReminders()
+	t.Cleanup(plain.Close)
+	if err := plain.Load(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := plain.AddEvent("Секрет", datetime.FormatLocal(time.Now().Add(48*time.Hour)), "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted := func() bool {
+		t.Helper()
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return storage.IsEncrypted(data)
+	}
+	open := func(passphrase string) *Calendar {
+		t.Helper()
+		s, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !encrypted() {
+			s.Migrate()
+		}
+		c := NewCalendar(storage.NewBlobRecords(s))
+		c.DisableReminders()
+		t.Cleanup(c.Close)
+		if err := c.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// Без изменений Save ничего не пишет, поэтому переход на шифрование требует Rewrite.
+	c := open("secret")
+	if err := c.Save(); err != nil || encrypted() {
+		t.Fatalf("Не ожидали запись при сохранении без изменений, получили: %v, зашифрован: %v", err, encrypted())
+	}
+	if err := c.Rewrite(); err != nil || !encrypted() {
+		t.Fatalf("Ожидали зашифрованный файл после Rewrite, получили: %v, зашифрован: %v", err, encrypted())
+	}
+
+	if err := c.ChangePassphrase("secret", "rotated"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := open("rotated").GetEvent(e.ID); err != nil {
+		t.Errorf("Ожидали событие после смены пароля, получили: %v", err)
+	}
+}
+
+func TestRewriteEncryptsEmptyCalendar(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	s, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCalendar(storage.NewBlobRecords(s))
+	c.DisableReminders()
+	t.Cleanup(c.Close)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Rewrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !storage.IsEncrypted(data) {
+		t.Errorf("Ожидали зашифрованный файл пустого календаря, получили %d байт открытым текстом", len(data))
+	}
+}
+
+func TestRecoverAfterPassphraseChange(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	open := func(passphrase string) (*Calendar, error) {
+		t.Helper()
+		s, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := NewCalendar(storage.NewBlobRecords(s))
+		c.DisableReminders()
+		t.Cleanup(c.Close)
+		return c, c.Load()
+	}
+
+	c, err := open("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	first, err := c.AddEvent("Первое", start, "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddEvent("Второе", start, "", events.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ChangePassphrase("secret", "rotated"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := open("secret"); !errors.Is(err, storage.ErrWrongPassphrase) {
+		t.Errorf("Ожидали ErrWrongPassphrase для старого пароля, получили: %v", err)
+	}
+	broken, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), "rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broken.Save([]byte("{broken")); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := open("rotated")
+	if err != nil {
+		t.Fatalf("Ожидали восстановление из резервной копии с новым паролем, получили: %v", err)
+	}
+	if _, err := recovered.GetEvent(first.ID); err != nil {
+		t.Errorf("Ожидали событие из резервной копии, получили: %v", err)
+	}
+	if len(recovered.LoadWarnings()) == 0 {
+		t.Error("Ожидали предупреждение о восстановлении, получили пустой список")
+	}
+}
+
+func TestFiredReminderAutosaved(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	c := NewCalendar(storage.NewJsonStorage(filename))
+	t.Cleanup(func() {
+		c.StopReminders()
+		c.Close()
+	})
+
+	e, err := c.AddEvent("Созвон", datetime.FormatLocal(time.Now().Add(48*time.Hour)), "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetEventReminder(e.ID, "Скоро", "1s"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Notification:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ожидали срабатывание напоминания")
+	}
+
+	saved := NewCalendar(storage.NewJsonStorage(filename))
+	saved.DisableReminders()
+	t.Cleanup(saved.Close)
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := saved.GetEvent(e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Reminders) != 1 || !got.Reminders[0].Sent {
+		t.Errorf("Ожидали одно отправленное напоминание, получили: %+v", got.Reminders)
+	}
+}
+
+func TestRestoredEventReportsMissedReminders(t *testing.T) {
+	c := newTestCalendar(t)
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	var ids []string
+	for _, title := range []string{"Первое", "Второе"} {
+		e, err := c.AddEvent(title, start, "", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.SetEventReminder(e.ID, "Скоро", "1s"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.DeleteEvent(e.ID); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+	time.Sleep(1500 * time.Millisecond)
+
+	// Отмена удаления возвращает второе событие через журнал.
+	if _, err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if missed := c.MissedReminders(); len(missed) != 1 || !strings.Contains(missed[0], "Второе") {
+		t.Errorf("Ожидали пропущенное напоминание события Второе после отмены, получили: %v", missed)
+	}
+	if _, err := c.RestoreEvent(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if missed := c.MissedReminders(); len(missed) != 2 || !strings.Contains(missed[1], "Первое") {
+		t.Errorf("Ожидали пропущенное напоминание события Первое после восстановления, получили: %v", missed)
+	}
+}
+
+func TestMovedEventReportsMissedReminder(t *testing.T) {
+	c := newTestCalendar(t)
+	e, err := c.AddEvent("Встреча", datetime.FormatLocal(time.Now().Add(48*time.Hour)), "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetEventReminder(e.ID, "Подготовиться", "-1h"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Напоминание за час до начала оказывается в прошлом.
+	soon := datetime.FormatLocal(time.Now().Add(30 * time.Minute))
+	if _, _, err := c.EditEvent(e.ID, "_", soon, "_", "_"); err != nil {
+		t.Fatal(err)
+	}
+	if missed := c.MissedReminders(); len(missed) != 1 || !strings.Contains(missed[0], "Подготовиться") {
+		t.Errorf("Ожидали пропущенное напоминание после переноса события, получили: %v", missed)
+	}
+	got, err := c.GetEvent(e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Reminders) != 1 || !got.Reminders[0].Sent {
+		t.Errorf("Ожидали отмеченное пропущенное напоминание, получили: %+v", got.Reminders)
+	}
+}
+
+func TestFiredRecurringReminderStaysScheduled(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	c := NewCalendar(storage.NewJsonStorage(filename))
+	t.Cleanup(func() {
+		c.StopReminders()
+		c.Close()
+	})
+
+	e, err := c.AddEvent("Планерка", datetime.FormatLocal(time.Now().Add(48*time.Hour)), "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetEventRecurrence(e.ID, "FREQ=DAILY"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.SetEventReminder(e.ID, "Скоро", "1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Notification:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ожидали срабатывания напоминания, его не было")
+	}
+
+	saved := NewCalendar(storage.NewJsonStorage(filename))
+	saved.DisableReminders()
+	t.Cleanup(saved.Close)
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := saved.GetEvent(e.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Reminders) != 1 {
+		t.Fatalf("Ожидали одно напоминание, получили: %+v", got.Reminders)
+	}
+	if want := r.At.Add(24 * time.Hour); got.Reminders[0].Sent || !got.Reminders[0].At.Equal(want) {
+		t.Errorf("Ожидали неотправленное напоминание на %s, получили: sent=%v, at=%s",
+			want, got.Reminders[0].Sent, got.Reminders[0].At)
+	}
+}
+
+func TestLoadMigratesLegacyFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	e, err := events.NewEvent("Встреча", datetime.FormatLocal(time.Now().Add(48*time.Hour)), "1h", events.PriorityHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := json.Marshal(map[string]*events.Event{e.ID: e})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCalendar(storage.NewJsonStorage(filename))
+	t.Cleanup(c.Close)
+	c.DisableReminders()
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.GetEvent(e.ID); err != nil || got.Title != e.Title {
+		t.Fatalf("Ожидали перенесенное событие, получили: %v, %v", got, err)
+	}
+	if len(c.LoadWarnings()) != 1 {
+		t.Errorf("Ожидали одно предупреждение о миграции, получили: %v", c.LoadWarnings())
+	}
+
+	backups, _ := filepath.Glob(filename + ".v1-*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("Ожидали одну копию до миграции, получили: %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != string(legacy) {
+		t.Error("Ожидали, что копия совпадет со старым файлом")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := storage.DataVersion(data); err != nil || v != storage.FormatVersion {
+		t.Errorf("Ожидали текущую версию формата после миграции, получили: %d, %v", v, err)
+	}
+
+	future := []byte(`{"format_version": 99, "events": {}}`)
+	if err := os.WriteFile(filename, future, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewCalendar(storage.NewJsonStorage(filename)).Load(); !errors.Is(err, storage.ErrUnsupportedFormat) {
+		t.Errorf("Ожидали ErrUnsupportedFormat для более нового формата, получили: %v", err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != string(future) {
+		t.Error("Не ожидали изменений в файле более нового формата")
+	}
+}
+
+func TestResolveIDPrefix(t *testing.T) {
+	c := newTestCalendar(t)
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	for _, id := range []string{"abc123", "abd456", "ABX789"} {
+		e, err := events.NewEvent("Событие "+id, start, "", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.ID = id
+		c.ImportEvents([]*events.Event{e})
+	}
+
+	tests := []struct {
+		prefix string
+		want   string
+		err    error
+	}{
+		{"abc123", "abc123", nil},
+		{"abc", "abc123", nil},
+		{"abx", "ABX789", nil},
+		{"ab", "", ErrAmbiguousID},
+		{"zzz", "", ErrEventNotFound},
+		{"", "", ErrEventNotFound},
+	}
+	for _, tt := range tests {
+		got, err := c.ResolveID(tt.prefix)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Ожидали %q, %v для префикса %q, получили: %q, %v", tt.want, tt.err, tt.prefix, got, err)
+		}
+	}
+
+	var ambiguous *AmbiguousIDError
+	_, err := c.DeleteEvent("ab")
+	if !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 3 {
+		t.Errorf("Ожидали неоднозначный ID с тремя кандидатами, получили: %v", err)
+	}
+	if _, err := c.DeleteEvent("abd"); err != nil {
+		t.Errorf("Не ожидали ошибку удаления по префиксу abd: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+var (
+	ErrCalendarNotFound = errors.New("календарь не найден")
+	ErrCalendarExists   = errors.New("календарь с таким именем уже есть")
+)
+
+// calendarsMetaKey — ключ списка календарей в служебных данных хранилища (storage.MetaStore).
+const calendarsMetaKey = "calendars"
+
+// calendarsFile — список календарей и текущий календарь в хранилище. Календарь по
+// умолчанию в список не записывается.
+type calendarsFile struct {
+	Active    string             `json:"active,omitempty"`
+	Calendars []*events.Calendar `json:"calendars"`
+}
+
+// CalendarSummary — календарь с числом его событий (без корзины).
+type CalendarSummary struct {
+	events.Calendar
+	Events int
+	Active bool
+}
+
+// loadCalendars читает список календарей и текущий календарь из того же хранилища,
+// что и события. Если хранилище не умеет хранить служебные данные, календари живут в памяти.
+func (c *Calendar) loadCalendars() error {
+	meta, ok := c.records.(storage.MetaStore)
+	if !ok {
+		return nil
+	}
+	data, err := meta.Meta(calendarsMetaKey)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения списка календарей: %w", err)
+	}
+	var file calendarsFile
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("ошибка разбора списка календарей %s: %w", c.storage.GetFilename(), err)
+		}
+	}
+
+	loaded := make(map[string]*events.Calendar, len(file.Calendars))
+	var warnings []string
+	for _, cal := range file.Calendars {
+		err := cal.Validate()
+		if err == nil && cal.Name == events.DefaultCalendar {
+			err = ErrCalendarExists
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Календарь %q пропущен: %v", cal.Name, err)
+			logger.Error(msg)
+			warnings = append(warnings, msg)
+			continue
+		}
+		loaded[cal.Name] = cal
+	}
+	active := events.DefaultCalendar
+	if _, ok := loaded[file.Active]; ok {
+		active = file.Active
+	} else if file.Active != "" && file.Active != events.DefaultCalendar {
+		msg := fmt.Sprintf("Текущий календарь %q не найден, используется %s", file.Active, events.DefaultCalendar)
+		logger.Error(msg)
+		warnings = append(warnings, msg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadWarnings = append(c.loadWarnings, warnings...)
+	c.calendars = loaded
+	c.activeCalendar = active
+	return nil
+}
+
+// saveCalendars записывает список календарей. Вызывающий должен держать блокировку календаря.
+func (c *Calendar) saveCalendars() error {
+	meta, ok := c.records.(storage.MetaStore)
+	if !ok {
+		return nil
+	}
+	file := calendarsFile{Calendars: make([]*events.Calendar, 0, len(c.calendars))}
+	if c.activeCalendar != events.DefaultCalendar {
+		file.Active = c.activeCalendar
+	}
+	for _, cal := range c.calendars {
+		file.Calendars = append(file.Calendars, cal)
+	}
+	sort.Slice(file.Calendars, func(i, j int) bool { return file.Calendars[i].Name < file.Calendars[j].Name })
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации JSON: %w", err)
+	}
+	return meta.PutMeta(calendarsMetaKey, data)
+}
+
+// findCalendar находит календарь по имени; пустое имя означает текущий календарь.
+// Календарь, которого нет в списке, но который указан в событиях, тоже находится.
+// Вызывающий должен держать блокировку календаря.
+func (c *Calendar) findCalendar(name string) (events.Calendar, error) {
+	name = events.NormalizeCalendarName(name)
+	if name == "" {
+		name = c.activeCalendar
+	}
+	if cal, ok := c.calendars[name]; ok {
+		return *cal, nil
+	}
+	if name == events.DefaultCalendar {
+		return events.Calendar{Name: events.DefaultCalendar}, nil
+	}
+	for _, e := range c.calendarEvents {
+		if e.Calendar == name {
+			return events.Calendar{Name: name}, nil
+		}
+	}
+	return events.Calendar{}, fmt.Errorf("%q: %w", name, ErrCalendarNotFound)
+}
+
+// GetCalendar возвращает календарь по имени; пустое имя означает текущий календарь.
+func (c *Calendar) GetCalendar(name string) (events.Calendar, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.findCalendar(name)
+}
+
+// Calendars возвращает календари с числом событий: сначала календарь по умолчанию,
+// затем остальные по имени. Календари, которые упоминаются в событиях, но отсутствуют
+// в списке, тоже выводятся, чтобы их события можно было найти и перенести.
+func (c *Calendar) Calendars() []CalendarSummary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	byName := map[string]*CalendarSummary{
+		events.DefaultCalendar: {Calendar: events.Calendar{Name: events.DefaultCalendar}},
+	}
+	for name, cal := range c.calendars {
+		byName[name] = &CalendarSummary{Calendar: *cal}
+	}
+	for _, e := range c.calendarEvents {
+		name := e.CalendarName()
+		s, ok := byName[name]
+		if !ok {
+			s = &CalendarSummary{Calendar: events.Calendar{Name: name}}
+			byName[name] = s
+		}
+		s.Events++
+	}
+
+	list := make([]CalendarSummary, 0, len(byName))
+	for name, s := range byName {
+		s.Active = name == c.activeCalendar
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].Name == events.DefaultCalendar) != (list[j].Name == events.DefaultCalendar) {
+			return list[i].Name == events.DefaultCalendar
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// CalendarNames возвращает имена календарей с префиксом prefix для автодополнения.
+func (c *Calendar) CalendarNames(prefix string) []string {
+	var names []string
+	for _, s := range c.Calendars() {
+		if hasIDPrefix(s.Name, prefix) {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// CreateCalendar добавляет календарь в список и сохраняет список.
+func (c *Calendar) CreateCalendar(name, color string, priority events.Priority) (events.Calendar, error) {
+	cal, err := events.NewCalendar(name, color, priority)
+	if err != nil {
+		return events.Calendar{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.calendars[cal.Name]; exists || cal.Name == events.DefaultCalendar {
+		return events.Calendar{}, fmt.Errorf("%q: %w", cal.Name, ErrCalendarExists)
+	}
+	c.calendars[cal.Name] = cal
+	if err := c.saveCalendars(); err != nil {
+		delete(c.calendars, cal.Name)
+		return events.Calendar{}, err
+	}
+	logger.Info(fmt.Sprintf("Создан календарь %s", cal.Name))
+	return *cal, nil
+}
+
+// UseCalendar делает календарь текущим: в него добавляются новые события.
+func (c *Calendar) UseCalendar(name string) (events.Calendar, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cal, err := c.findCalendar(name)
+	if err != nil {
+		return events.Calendar{}, err
+	}
+	prev := c.activeCalendar
+	c.activeCalendar = cal.Name
+	if err := c.saveCalendars(); err != nil {
+		c.activeCalendar = prev
+		return events.Calendar{}, err
+	}
+	logger.Info(fmt.Sprintf("Текущий календарь: %s", cal.Name))
+	return cal, nil
+}
+
+// MoveEvent переносит событие в другой календарь; перенос в тот же календарь ничего
+// не меняет.
+func (c *Calendar) MoveEvent(id string, name string) (*events.Event, error) {
+	var moved *events.Event
+	err := c.mutate(OpMove, func() error {
+		cal, err := c.findCalendar(name)
+		if err != nil {
+			return err
+		}
+		e, err := c.lookup(id)
+		if err != nil {
+			return err
+		}
+		if e.CalendarName() != cal.Name {
+			c.track(e.ID)
+			e.Calendar = eventCalendar(cal.Name)
+		}
+		moved = e.Clone()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// eventCalendar возвращает значение поля Calendar события для календаря name.
+func eventCalendar(name string) string {
+	if name == events.DefaultCalendar {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,223 @@
+package calendar
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestCalendars(t *testing.T) {
+	stores := map[string]func(t *testing.T, dir string) storage.Store{
+		"json": func(t *testing.T, dir string) storage.Store {
+			return storage.NewJsonStorage(filepath.Join(dir, "calendar.json"))
+		},
+		"encrypted": func(t *testing.T, dir string) storage.Store {
+			s, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filepath.Join(dir, "calendar.json")), "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		"sqlite": func(t *testing.T, dir string) storage.Store {
+			db, err := storage.NewSqliteStorage(filepath.Join(dir, "calendar.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			return db
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			testCalendars(t, newStore)
+		})
+	}
+}
+
+// testCalendars проверяет календари и то, что их список сохраняется в том же
+// хранилище, что и события.
+func testCalendars(t *testing.T, newStore func(t *testing.T, dir string) storage.Store) {
+	dir := t.TempDir()
+	open := func() *Calendar {
+		c := NewCalendar(newStore(t, dir))
+		c.DisableReminders()
+		t.Cleanup(c.Close)
+		if err := c.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	c := open()
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour).Truncate(time.Hour))
+
+	if _, err := c.CreateCalendar("Work", "Blue", events.PriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateCalendar("личное", "#00ff00", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"work", events.DefaultCalendar} {
+		if _, err := c.CreateCalendar(name, "", ""); !errors.Is(err, ErrCalendarExists) {
+			t.Errorf("Ожидали ErrCalendarExists для %q, получили: %v", name, err)
+		}
+	}
+	invalid := []struct {
+		name, color string
+		want        error
+	}{
+		{"два слова", "", events.ErrInvalidCalendarName},
+		{"", "", events.ErrInvalidCalendarName},
+		{"team", "purple", events.ErrInvalidColor},
+	}
+	for _, tt := range invalid {
+		if _, err := c.CreateCalendar(tt.name, tt.color, ""); !errors.Is(err, tt.want) {
+			t.Errorf("Ожидали ошибку %v для %q (%q), получили: %v", tt.want, tt.name, tt.color, err)
+		}
+	}
+
+	general, err := c.AddEvent("Общее", start, "1h", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if general.CalendarName() != events.DefaultCalendar || general.Calendar != "" {
+		t.Errorf("Ожидали календарь по умолчанию, получили: %q", general.Calendar)
+	}
+	if _, err := c.UseCalendar("нет"); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("Ожидали ErrCalendarNotFound, получили: %v", err)
+	}
+	if _, err := c.UseCalendar("WORK"); err != nil {
+		t.Fatal(err)
+	}
+	call, err := c.AddEvent("Созвон", start, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Calendar != "work" || call.Priority != events.PriorityHigh {
+		t.Errorf("Ожидали work/high из активного календаря, получили: %q/%q", call.Calendar, call.Priority)
+	}
+	if _, err := c.AddEventTo("личное", "Без приоритета", start, "", ""); !errors.Is(err, events.ErrInvalidPriority) {
+		t.Errorf("Ожидали ErrInvalidPriority без приоритета, получили: %v", err)
+	}
+
+	moved, err := c.MoveEvent(general.ID[:8], "личное")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Calendar != "личное" {
+		t.Errorf("Ожидали календарь личное после переноса, получили: %q", moved.Calendar)
+	}
+	if _, err := c.MoveEvent(general.ID, "нет"); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("Ожидали ErrCalendarNotFound при переносе, получили: %v", err)
+	}
+	if got := c.Query(Query{Calendar: "личное"}).Occurrences; len(got) != 1 || got[0].Event.ID != general.ID {
+		t.Errorf("Ожидали одно перенесенное событие в календаре личное, получили: %v", got)
+	}
+	if got := c.Query(Query{Calendar: events.DefaultCalendar}).Total; got != 0 {
+		t.Errorf("Ожидали 0 событий в календаре по умолчанию, получили: %d", got)
+	}
+
+	if _, err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := c.GetEvent(general.ID); e.CalendarName() != events.DefaultCalendar {
+		t.Errorf("Ожидали календарь по умолчанию после отмены, получили: %q", e.CalendarName())
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "calendars*")); len(files) > 0 {
+		t.Errorf("Не ожидали отдельный файл со списком календарей, получили: %v", files)
+	}
+
+	c = open()
+	if cal, err := c.GetCalendar(""); err != nil || cal.Name != "work" || cal.Color != "blue" {
+		t.Errorf("Ожидали активный календарь work (blue) после перезагрузки, получили: %+v, %v", cal, err)
+	}
+	want := []struct {
+		name   string
+		events int
+		active bool
+	}{
+		{events.DefaultCalendar, 1, false},
+		{"work", 1, true},
+		{"личное", 0, false},
+	}
+	list := c.Calendars()
+	if len(list) != len(want) {
+		t.Fatalf("Ожидали %d календаря, получили: %+v", len(want), list)
+	}
+	for i, w := range want {
+		if list[i].Name != w.name || list[i].Events != w.events || list[i].Active != w.active {
+			t.Errorf("Ожидали календарь %+v на позиции %d, получили: %+v", w, i, list[i])
+		}
+	}
+}
+
+func TestImportKeepsCalendar(t *testing.T) {
+	c := NewCalendar(storage.NewJsonStorage(filepath.Join(t.TempDir(), "calendar.json")))
+	c.DisableReminders()
+	t.Cleanup(c.Close)
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour).Truncate(time.Hour))
+
+	for _, name := range []string{"work", "home"} {
+		if _, err := c.CreateCalendar(name, "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved, err := c.AddEvent("Созвон", start, "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MoveEvent(moved.ID, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UseCalendar("home"); err != nil {
+		t.Fatal(err)
+	}
+
+	newEvent := func(title, calendar string) *events.Event {
+		e, err := events.NewEvent(title, start, "", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Calendar = calendar
+		return e
+	}
+	updated, err := c.GetEvent(moved.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated.Calendar = ""
+	updated.Title = "Созвон с командой"
+	plain := newEvent("Без календаря", "")
+	general := newEvent("Общее", events.DefaultCalendar)
+	named := newEvent("Рабочее", "work")
+
+	if added, changed := c.ImportEvents([]*events.Event{updated, plain, general, named}); added != 3 || changed != 1 {
+		t.Fatalf("Ожидали 3 новых и 1 обновлённое событие, получили %d и %d", added, changed)
+	}
+	want := map[string]string{
+		moved.ID:   "work",
+		plain.ID:   "home",
+		general.ID: events.DefaultCalendar,
+		named.ID:   "work",
+	}
+	for id, calendar := range want {
+		e, err := c.GetEvent(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.CalendarName() != calendar {
+			t.Errorf("Ожидали событие %q в календаре %s, получили %s", e.Title, calendar, e.CalendarName())
+		}
+	}
+	if e, _ := c.GetEvent(general.ID); e.Calendar != "" {
+		t.Errorf("Ожидали пустое поле Calendar для календаря по умолчанию, получили %q", e.Calendar)
+	}
+}
@@ -0,0 +1,137 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+var ErrConflict = errors.New("событие пересекается с другими событиями")
+
+// ConflictError возвращается AddEvent и EditEvent в строгом режиме, если событие
+// пересекается с уже существующими.
+type ConflictError struct {
+	Conflicts []events.Occurrence
+}
+
+func (e *ConflictError) Error() string {
+	list := make([]string, 0, len(e.Conflicts))
+	for _, o := range e.Conflicts {
+		list = append(list, fmt.Sprintf("\"%s\" (%s)", o.Title, datetime.FormatRange(o.StartAt, o.EndAt, o.Event.AllDay)))
+	}
+	return fmt.Sprintf("%v: %s", ErrConflict, strings.Join(list, ", "))
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+type Conflict struct {
+	First  events.Occurrence
+	Second events.Occurrence
+}
+
+// busyEnd возвращает окончание занятого времени; для событий без окончания
+// используется config.DefaultEventDuration.
+func busyEnd(o events.Occurrence) time.Time {
+	if !o.EndAt.IsZero() {
+		return o.EndAt
+	}
+	return o.StartAt.Add(config.DefaultEventDuration)
+}
+
+// conflicting сообщает, пересекаются ли вхождения разных событий. События на весь
+// день сравниваются только друг с другом: отпуск или день рождения не занимают
+// время встреч.
+func conflicting(a, b events.Occurrence) bool {
+	if a.Event.ID == b.Event.ID || a.Event.AllDay != b.Event.AllDay {
+		return false
+	}
+	return a.StartAt.Before(busyEnd(b)) && b.StartAt.Before(busyEnd(a))
+}
+
+func (c *Calendar) SetStrictConflicts(strict bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.strictConflicts = strict
+}
+
+// EventConflicts возвращает вхождения других событий, пересекающиеся с событием id.
+// Повторяющееся событие проверяется на config.RecurrenceListHorizon вперёд.
+func (c *Calendar) EventConflicts(id string) ([]events.Occurrence, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.conflictsFor(e), nil
+}
+
+// conflictsFor вызывается под блокировкой календаря; e может ещё не входить в календарь.
+func (c *Calendar) conflictsFor(e *events.Event) []events.Occurrence {
+	var mine []events.Occurrence
+	if e.IsRecurring() {
+		now := time.Now()
+		mine = e.Occurrences(now, now.Add(config.RecurrenceListHorizon))
+	} else {
+		mine = e.Occurrences(e.StartAt, e.EndTime().Add(time.Second))
+	}
+
+	seen := make(map[string]bool)
+	var out []events.Occurrence
+	for _, other := range c.calendarEvents {
+		if other.ID == e.ID {
+			continue
+		}
+		var clone *events.Event
+		for _, m := range mine {
+			for _, o := range other.Occurrences(m.StartAt.Add(-config.DefaultEventDuration), busyEnd(m)) {
+				key := o.Event.ID + "/" + o.RecurrenceID.String()
+				if seen[key] || !conflicting(m, o) {
+					continue
+				}
+				seen[key] = true
+				if clone == nil {
+					clone = other.Clone()
+				}
+				o.Event = clone
+				out = append(out, o)
+			}
+		}
+	}
+	sortOccurrences(out)
+	return out
+}
+
+// FindConflicts возвращает все пары пересекающихся вхождений в [from, to).
+func (c *Calendar) FindConflicts(from, to time.Time) []Conflict {
+	c.mu.RLock()
+	var list []events.Occurrence
+	for _, e := range c.calendarEvents {
+		for _, o := range e.Clone().Occurrences(from.Add(-config.DefaultEventDuration), to) {
+			if busyEnd(o).After(from) {
+				list = append(list, o)
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	sortOccurrences(list)
+	var out []Conflict
+	for i := range list {
+		end := busyEnd(list[i])
+		for j := i + 1; j < len(list) && list[j].StartAt.Before(end); j++ {
+			if conflicting(list[i], list[j]) {
+				out = append(out, Conflict{First: list[i], Second: list[j]})
+			}
+		}
+	}
+	return out
+}
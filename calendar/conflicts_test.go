@@ -0,0 +1,61 @@
+package calendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+func TestConflicts(t *testing.T) {
+	c := newTestCalendar(t)
+	base := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	at := func(d time.Duration) string { return datetime.FormatLocal(base.Add(d)) }
+
+	meeting, err := c.AddEvent("Встреча", at(0), "1h", events.PriorityHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Без окончания событие занимает config.DefaultEventDuration.
+	call, err := c.AddEvent("Созвон", at(30*time.Minute), "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddEvent("Обед", at(3*time.Hour), "", events.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddEvent("Отпуск", datetime.FormatLocalDate(base), "", events.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := c.EventConflicts(meeting.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Event.ID != call.ID {
+		t.Errorf("Ожидали только конфликт с %s, получили: %v", call.ID, list)
+	}
+
+	found := c.FindConflicts(base.Add(-time.Hour), base.Add(24*time.Hour))
+	if len(found) != 1 {
+		t.Errorf("Ожидали одну пару конфликтов, получили: %d", len(found))
+	}
+
+	c.SetStrictConflicts(true)
+	_, err = c.AddEvent("Другая встреча", at(15*time.Minute), "25m", events.PriorityMedium)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Conflicts) != 2 {
+		t.Fatalf("Ожидали конфликт с двумя событиями в строгом режиме, получили: %v", err)
+	}
+	if _, _, err := c.EditEvent(call.ID, "_", at(3*time.Hour), "_", "_"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Ожидали ErrConflict при изменении в строгом режиме, получили: %v", err)
+	}
+	if _, _, err := c.EditEvent(call.ID, "_", at(5*time.Hour), "_", "_"); err != nil {
+		t.Errorf("Не ожидали ошибку изменения без конфликта: %v", err)
+	}
+	if got := len(c.GetEvents()); got != 4 {
+		t.Errorf("Ожидали 4 события, получили: %d", got)
+	}
+}
@@ -0,0 +1,151 @@
+package calendar
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+)
+
+var (
+	ErrInvalidSlotDuration = errors.New("длительность слота должна быть больше нуля")
+	ErrInvalidWorkHours    = errors.New("неверные рабочие часы")
+	ErrNoFreeSlot          = errors.New("свободного времени не найдено")
+)
+
+// SlotQuery описывает поиск свободного времени в [From, To). WorkStart и WorkEnd —
+// смещения от полуночи; если оба нулевые, рабочим считается весь день.
+// Step выравнивает начало слота, Limit ограничивает число слотов (0 — без ограничения).
+type SlotQuery struct {
+	Duration     time.Duration
+	From         time.Time
+	To           time.Time
+	WorkStart    time.Duration
+	WorkEnd      time.Duration
+	SkipWeekends bool
+	Step         time.Duration
+	Limit        int
+}
+
+type Slot struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (s Slot) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+type interval struct {
+	start time.Time
+	end   time.Time
+}
+
+func (q SlotQuery) Validate() error {
+	if q.Duration <= 0 {
+		return ErrInvalidSlotDuration
+	}
+	if q.WorkStart < 0 || q.WorkEnd > datetime.Day || q.WorkStart > q.WorkEnd ||
+		(q.WorkStart == q.WorkEnd && q.WorkStart != 0) {
+		return ErrInvalidWorkHours
+	}
+	return nil
+}
+
+// FreeSlots возвращает свободные интервалы не короче q.Duration. Занятым считается
+// время событий с окончанием или config.DefaultEventDuration для событий без него;
+// события на весь день время не занимают.
+func (c *Calendar) FreeSlots(q SlotQuery) ([]Slot, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	busy := c.busyIntervals(q.From, q.To)
+	var slots []Slot
+	for day := datetime.StartOfDay(q.From); day.Before(q.To); day = day.AddDate(0, 0, 1) {
+		if q.SkipWeekends && datetime.WeekdayIndex(day) >= 5 {
+			continue
+		}
+		start, end := day, day.AddDate(0, 0, 1)
+		if q.WorkStart != 0 || q.WorkEnd != 0 {
+			start, end = wallClock(day, q.WorkStart), wallClock(day, q.WorkEnd)
+		}
+		if start.Before(q.From) {
+			start = q.From
+		}
+		if end.After(q.To) {
+			end = q.To
+		}
+
+		for _, free := range subtract(interval{start, end}, busy) {
+			free.start = alignUp(free.start, q.Step)
+			if free.end.Sub(free.start) < q.Duration {
+				continue
+			}
+			slots = append(slots, Slot{Start: free.start, End: free.end})
+			if q.Limit > 0 && len(slots) == q.Limit {
+				return slots, nil
+			}
+		}
+	}
+	return slots, nil
+}
+
+// wallClock возвращает момент, когда на часах дня day показывает время offset от
+// полуночи. В дни перехода на летнее время это не то же, что day.Add(offset).
+func wallClock(day time.Time, offset time.Duration) time.Time {
+	y, m, d := day.Date()
+	h, rest := offset/time.Hour, offset%time.Hour
+	return time.Date(y, m, d, int(h), int(rest/time.Minute), int(rest%time.Minute/time.Second), 0, day.Location())
+}
+
+func (c *Calendar) busyIntervals(from, to time.Time) []interval {
+	c.mu.RLock()
+	var busy []interval
+	for _, e := range c.calendarEvents {
+		if e.AllDay {
+			continue
+		}
+		for _, o := range e.Clone().Occurrences(from.Add(-datetime.Day), to) {
+			busy = append(busy, interval{o.StartAt, busyEnd(o)})
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(busy, func(i, j int) bool { return busy[i].start.Before(busy[j].start) })
+	return busy
+}
+
+// subtract вычитает из window отсортированные по началу занятые интервалы.
+func subtract(window interval, busy []interval) []interval {
+	var out []interval
+	cur := window.start
+	for _, b := range busy {
+		if !b.end.After(cur) {
+			continue
+		}
+		if !b.start.Before(window.end) {
+			break
+		}
+		if b.start.After(cur) {
+			out = append(out, interval{cur, b.start})
+		}
+		cur = b.end
+	}
+	if cur.Before(window.end) {
+		out = append(out, interval{cur, window.end})
+	}
+	return out
+}
+
+func alignUp(t time.Time, step time.Duration) time.Time {
+	if step <= 0 {
+		return t
+	}
+	aligned := t.Truncate(step)
+	if aligned.Before(t) {
+		aligned = aligned.Add(step)
+	}
+	return aligned
+}
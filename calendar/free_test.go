@@ -0,0 +1,95 @@
+package calendar
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+func TestFreeSlots(t *testing.T) {
+	c := newTestCalendar(t)
+	// Понедельник через неделю-две, чтобы все события были в будущем.
+	day := datetime.StartOfWeek(time.Now()).AddDate(0, 0, 14)
+	at := func(d time.Duration) string { return datetime.FormatLocal(day.Add(d)) }
+
+	if _, err := c.AddEvent("Планерка", at(10*time.Hour), "1h", events.PriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	// Без окончания событие занимает config.DefaultEventDuration.
+	if _, err := c.AddEvent("Созвон", at(13*time.Hour), "", events.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddEvent("Отпуск", datetime.FormatLocalDate(day), "", events.PriorityLow); err != nil {
+		t.Fatal(err)
+	}
+
+	q := SlotQuery{
+		Duration:  time.Hour,
+		From:      day,
+		To:        day.AddDate(0, 0, 1),
+		WorkStart: 9 * time.Hour,
+		WorkEnd:   18 * time.Hour,
+	}
+	slots, err := c.FreeSlots(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]time.Duration{{9 * time.Hour, 10 * time.Hour}, {11 * time.Hour, 13 * time.Hour}, {14 * time.Hour, 18 * time.Hour}}
+	if len(slots) != len(want) {
+		t.Fatalf("Ожидали %d свободных окна, получили: %v", len(want), slots)
+	}
+	for i, w := range want {
+		if !slots[i].Start.Equal(day.Add(w[0])) || !slots[i].End.Equal(day.Add(w[1])) {
+			t.Errorf("Окно %d: ожидали %v-%v, получили %v-%v", i, w[0], w[1], slots[i].Start, slots[i].End)
+		}
+	}
+
+	q.Duration = 3 * time.Hour
+	if slots, _ := c.FreeSlots(q); len(slots) != 1 || !slots[0].Start.Equal(day.Add(14*time.Hour)) {
+		t.Errorf("Ожидали одно трехчасовое окно с 14:00, получили: %v", slots)
+	}
+
+	// Суббота и воскресенье пропускаются.
+	q.Duration = time.Hour
+	q.From, q.To = day.AddDate(0, 0, 5), day.AddDate(0, 0, 8)
+	q.SkipWeekends = true
+	q.Limit = 1
+	if slots, _ := c.FreeSlots(q); len(slots) != 1 || !slots[0].Start.Equal(day.AddDate(0, 0, 7).Add(9*time.Hour)) {
+		t.Errorf("Ожидали первое окно в понедельник после выходных, получили: %v", slots)
+	}
+
+	if _, err := c.FreeSlots(SlotQuery{From: day, To: day.AddDate(0, 0, 1)}); !errors.Is(err, ErrInvalidSlotDuration) {
+		t.Errorf("Ожидали ErrInvalidSlotDuration, получили: %v", err)
+	}
+	q.WorkStart, q.WorkEnd = 18*time.Hour, 9*time.Hour
+	if _, err := c.FreeSlots(q); !errors.Is(err, ErrInvalidWorkHours) {
+		t.Errorf("Ожидали ErrInvalidWorkHours, получили: %v", err)
+	}
+}
+
+func TestWallClockDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	// 31 марта 2030 года часы переводятся вперёд, а 27 октября — назад.
+	for _, date := range []string{"2030-03-31", "2030-10-27"} {
+		day, err := time.ParseInLocation(datetime.DateLayoutFormat, date, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, end := wallClock(day, 9*time.Hour), wallClock(day, 18*time.Hour+30*time.Minute)
+		if got := start.Format(datetime.LayoutFormat); got != date+" 09:00" {
+			t.Errorf("%s: ожидали начало в 09:00, получили: %s", date, got)
+		}
+		if got := end.Format(datetime.LayoutFormat); got != date+" 18:30" {
+			t.Errorf("%s: ожидали окончание в 18:30, получили: %s", date, got)
+		}
+		if next := wallClock(day, datetime.Day); !next.Equal(day.AddDate(0, 0, 1)) {
+			t.Errorf("%s: ожидали полночь следующего дня, получили: %s", date, next)
+		}
+	}
+}
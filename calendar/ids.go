@@ -0,0 +1,71 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+// AmbiguousIDError возвращается, когда префикс ID подходит к нескольким событиям.
+type AmbiguousIDError struct {
+	Prefix     string
+	Candidates []Candidate
+}
+
+type Candidate struct {
+	ID    string
+	Title string
+}
+
+func (e *AmbiguousIDError) Error() string {
+	list := make([]string, 0, len(e.Candidates))
+	for _, c := range e.Candidates {
+		list = append(list, fmt.Sprintf("%s (%s)", c.ID, c.Title))
+	}
+	return fmt.Sprintf("id=%q: %v: %s", e.Prefix, ErrAmbiguousID, strings.Join(list, ", "))
+}
+
+func (e *AmbiguousIDError) Unwrap() error {
+	return ErrAmbiguousID
+}
+
+// ResolveID возвращает полный ID события по ID или его однозначному префиксу.
+func (c *Calendar) ResolveID(prefix string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, err := c.lookup(prefix)
+	if err != nil {
+		return "", err
+	}
+	return e.ID, nil
+}
+
+// IDs возвращает ID и названия событий, начинающиеся с prefix без учёта регистра,
+// отсортированные по ID.
+func (c *Calendar) IDs(prefix string) []Candidate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.candidates(prefix)
+}
+
+func (c *Calendar) candidates(prefix string) []Candidate {
+	return candidatesIn(c.calendarEvents, prefix)
+}
+
+func candidatesIn(m map[string]*events.Event, prefix string) []Candidate {
+	var list []Candidate
+	for id, e := range m {
+		if hasIDPrefix(id, prefix) {
+			list = append(list, Candidate{ID: id, Title: e.Title})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func hasIDPrefix(id, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(id), strings.ToLower(prefix))
+}
@@ -0,0 +1,316 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+type Op string
+
+const (
+	OpAdd          Op = "add"
+	OpUpdate       Op = "update"
+	OpDelete       Op = "delete"
+	OpRemind       Op = "remind"
+	OpRemindCancel Op = "remind-cancel"
+	OpRepeat       Op = "repeat"
+	OpRepeatCancel Op = "repeat-cancel"
+	OpRepeatSkip   Op = "repeat-skip"
+	OpRepeatEdit   Op = "repeat-edit"
+	OpImport       Op = "import"
+	OpRestore      Op = "restore"
+	OpPurge        Op = "trash-empty"
+	OpMove         Op = "move"
+	OpUndo         Op = "undo"
+	OpRedo         Op = "redo"
+)
+
+var (
+	ErrNothingToUndo = errors.New("нечего отменять")
+	ErrNothingToRedo = errors.New("нечего повторять")
+)
+
+// Change — состояние события до и после изменения в JSON; пустое состояние значит,
+// что события не было (добавление) или не стало (удаление).
+type Change struct {
+	EventID string          `json:"event_id"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	After   json.RawMessage `json:"after,omitempty"`
+}
+
+// JournalEntry — одно изменение календаря. Для undo и redo Ref — номер записи,
+// изменение которой отменено или повторено.
+type JournalEntry struct {
+	Seq     int64     `json:"seq"`
+	At      time.Time `json:"at"`
+	Op      Op        `json:"op"`
+	Ref     int64     `json:"ref,omitempty"`
+	Changes []Change  `json:"changes"`
+}
+
+// SetJournal подключает журнал изменений и восстанавливает из него историю и стеки
+// undo/redo. Без журнала история хранится только в памяти до конца сессии.
+func (c *Calendar) SetJournal(j *storage.Journal) error {
+	lines, err := j.Lines()
+	if err != nil {
+		return err
+	}
+
+	var entries []JournalEntry
+	var warnings []string
+	for i, line := range lines {
+		var e JournalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			msg := fmt.Sprintf("Журнал %s: строка %d пропущена: %v", j.GetFilename(), i+1, err)
+			logger.Error(msg)
+			warnings = append(warnings, msg)
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadWarnings = append(c.loadWarnings, warnings...)
+	c.journal = j
+	c.history = nil
+	c.undoStack, c.redoStack = nil, nil
+	for _, e := range entries {
+		c.pushHistory(e)
+	}
+	return nil
+}
+
+// pushHistory добавляет запись в историю и обновляет стеки undo/redo.
+func (c *Calendar) pushHistory(e JournalEntry) {
+	c.history = append(c.history, e)
+	switch e.Op {
+	case OpUndo:
+		if n := len(c.undoStack); n > 0 && c.undoStack[n-1] == e.Ref {
+			c.undoStack = c.undoStack[:n-1]
+		}
+		c.redoStack = append(c.redoStack, e.Ref)
+	case OpRedo:
+		if n := len(c.redoStack); n > 0 && c.redoStack[n-1] == e.Ref {
+			c.redoStack = c.redoStack[:n-1]
+		}
+		c.undoStack = append(c.undoStack, e.Ref)
+	default:
+		c.undoStack = append(c.undoStack, e.Seq)
+		c.redoStack = nil
+	}
+}
+
+// track запоминает состояние события до изменения; вызывается внутри mutate перед
+// тем, как событие будет изменено, добавлено или удалено.
+func (c *Calendar) track(id string) {
+	if c.pending == nil {
+		c.pending = make(map[string]json.RawMessage)
+	}
+	if _, ok := c.pending[id]; ok {
+		return
+	}
+	c.pending[id] = c.snapshot(id)
+}
+
+func (c *Calendar) snapshot(id string) json.RawMessage {
+	e, ok := c.calendarEvents[id]
+	if !ok {
+		if e, ok = c.trash[id]; !ok {
+			return nil
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Ошибка сериализации события ID=%s для журнала: %v", id, err))
+		return nil
+	}
+	return data
+}
+
+// commitJournal записывает изменения событий, отмеченных track. Вызывается под
+// блокировкой календаря после успешного изменения. Обычное изменение без видимых
+// отличий не записывается; undo и redo записываются всегда, чтобы сдвинуть стеки.
+func (c *Calendar) commitJournal(op Op, ref int64) {
+	pending := c.pending
+	c.pending = nil
+
+	var changes []Change
+	for id, before := range pending {
+		after := c.snapshot(id)
+		if !bytes.Equal(before, after) {
+			changes = append(changes, Change{EventID: id, Before: before, After: after})
+		}
+	}
+	if len(changes) == 0 && op != OpUndo && op != OpRedo {
+		return
+	}
+
+	entry := JournalEntry{Seq: 1, At: time.Now().UTC(), Op: op, Ref: ref, Changes: changes}
+	if n := len(c.history); n > 0 {
+		entry.Seq = c.history[n-1].Seq + 1
+	}
+	c.pushHistory(entry)
+
+	if c.journal == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = c.journal.Append(data)
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Ошибка записи в журнал: %v", err))
+	}
+}
+
+func (c *Calendar) entry(seq int64) (JournalEntry, bool) {
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if c.history[i].Seq == seq {
+			return c.history[i], true
+		}
+	}
+	return JournalEntry{}, false
+}
+
+// Undo отменяет последнее изменение, которое ещё не отменено, и возвращает его запись.
+func (c *Calendar) Undo() (JournalEntry, error) {
+	return c.replay(OpUndo)
+}
+
+// Redo повторяет последнее отменённое изменение и возвращает его запись.
+func (c *Calendar) Redo() (JournalEntry, error) {
+	return c.replay(OpRedo)
+}
+
+func (c *Calendar) replay(op Op) (JournalEntry, error) {
+	var target JournalEntry
+	err := c.mutate(op, func() error {
+		stack, empty := c.undoStack, ErrNothingToUndo
+		if op == OpRedo {
+			stack, empty = c.redoStack, ErrNothingToRedo
+		}
+		if len(stack) == 0 {
+			return empty
+		}
+		var ok bool
+		if target, ok = c.entry(stack[len(stack)-1]); !ok {
+			return empty
+		}
+
+		for _, ch := range target.Changes {
+			state := ch.Before
+			if op == OpRedo {
+				state = ch.After
+			}
+			c.track(ch.EventID)
+			if err := c.restoreEvent(ch.EventID, state); err != nil {
+				c.pending = nil
+				return err
+			}
+		}
+		c.commitJournal(op, target.Seq)
+		return nil
+	})
+	return target, err
+}
+
+// restoreEvent заменяет событие сохранённым состоянием; пустое состояние удаляет его,
+// а состояние с DeletedAt возвращает событие в корзину.
+func (c *Calendar) restoreEvent(id string, state json.RawMessage) error {
+	if old, ok := c.calendarEvents[id]; ok {
+		stopReminders(old)
+		delete(c.calendarEvents, id)
+	}
+	delete(c.trash, id)
+	if len(state) == 0 {
+		return nil
+	}
+
+	var e events.Event
+	if err := json.Unmarshal(state, &e); err != nil {
+		return fmt.Errorf("ошибка восстановления события %s: %w", id, err)
+	}
+	if !e.DeletedAt.IsZero() {
+		c.trash[id] = &e
+		return nil
+	}
+	c.calendarEvents[id] = &e
+	c.armReminders(&e, time.Now())
+	return nil
+}
+
+// History возвращает записи журнала; с непустым id — только изменения этого события
+// (id может быть однозначным началом ID, в том числе удалённого события).
+func (c *Calendar) History(id string) ([]JournalEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if id == "" {
+		return append([]JournalEntry(nil), c.history...), nil
+	}
+
+	full, err := c.historyID(id)
+	if err != nil {
+		return nil, err
+	}
+	var list []JournalEntry
+	for _, e := range c.history {
+		for _, ch := range e.Changes {
+			if ch.EventID == full {
+				e.Changes = []Change{ch}
+				list = append(list, e)
+				break
+			}
+		}
+	}
+	return list, nil
+}
+
+func (c *Calendar) historyID(prefix string) (string, error) {
+	if e, err := c.lookup(prefix); err == nil {
+		return e.ID, nil
+	} else if !errors.Is(err, ErrEventNotFound) {
+		return "", err
+	}
+
+	seen := make(map[string]bool)
+	var candidates []Candidate
+	for _, e := range c.history {
+		for _, ch := range e.Changes {
+			if seen[ch.EventID] || !hasIDPrefix(ch.EventID, prefix) {
+				continue
+			}
+			seen[ch.EventID] = true
+			candidates = append(candidates, Candidate{ID: ch.EventID, Title: ch.Title()})
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("id=%q: %w", prefix, ErrEventNotFound)
+	case 1:
+		return candidates[0].ID, nil
+	default:
+		return "", &AmbiguousIDError{Prefix: prefix, Candidates: candidates}
+	}
+}
+
+// Title возвращает название события после изменения, а для удаления — до него.
+func (ch Change) Title() string {
+	state := ch.After
+	if len(state) == 0 {
+		state = ch.Before
+	}
+	var head struct {
+		Title string `json:"title"`
+	}
+	_ = json.Unmarshal(state, &head)
+	return head.Title
+}
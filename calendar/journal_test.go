@@ -0,0 +1,110 @@
+package calendar
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestJournalUndoRedo(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.jsonl")
+	c := newTestCalendar(t)
+	if err := c.SetJournal(storage.NewJournal(journal)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour).Truncate(time.Hour))
+	e, err := c.AddEvent("Встреча", start, "1h", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.EditEvent(e.ID, "Созвон", "_", "_", "_"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SetEventReminder(e.ID, "Скоро", "-15m"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.DeleteEvent(e.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	title := func() string {
+		got, err := c.GetEvent(e.ID)
+		if err != nil {
+			return ""
+		}
+		return got.Title
+	}
+
+	if entry, err := c.Undo(); err != nil || entry.Op != OpDelete {
+		t.Fatalf("Ожидали отмену удаления, получили: %v, %v", entry.Op, err)
+	}
+	got, err := c.GetEvent(e.ID)
+	if err != nil || got.Title != "Созвон" || len(got.Reminders) != 1 {
+		t.Fatalf("Ожидали восстановленное событие с напоминанием, получили: %v, %v", got, err)
+	}
+	for range 2 {
+		if _, err := c.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if title() != "Встреча" {
+		t.Errorf("Ожидали название Встреча после отмены изменения, получили %q", title())
+	}
+	if _, err := c.Redo(); err != nil || title() != "Созвон" {
+		t.Errorf("Ожидали название Созвон после повтора, получили %q, %v", title(), err)
+	}
+
+	// Стеки восстанавливаются из журнала в новой сессии.
+	reloaded := newTestCalendar(t)
+	reloaded.ImportEvents(c.GetEvents())
+	if err := reloaded.SetJournal(storage.NewJournal(journal)); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := reloaded.Redo(); err != nil || entry.Op != OpRemind {
+		t.Errorf("Ожидали повтор напоминания после перезагрузки, получили: %v, %v", entry.Op, err)
+	}
+
+	// Новое изменение очищает стек redo.
+	if _, _, err := c.EditEvent(e.ID, "_", "_", "_", events.PriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Ожидали ErrNothingToRedo после нового изменения, получили: %v", err)
+	}
+
+	history, err := c.History(e.ID[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []Op
+	for _, h := range history {
+		ops = append(ops, h.Op)
+	}
+	want := []Op{OpAdd, OpUpdate, OpRemind, OpDelete, OpUndo, OpUndo, OpUndo, OpRedo, OpUpdate}
+	if len(ops) != len(want) {
+		t.Fatalf("Ожидали историю %v, получили %v", want, ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Fatalf("Ожидали историю %v, получили %v", want, ops)
+		}
+	}
+	if h := history[1].Changes[0]; h.Title() != "Созвон" || len(h.Before) == 0 || len(h.After) == 0 {
+		t.Errorf("Ожидали изменение названия с состоянием до и после, получили: %+v", h)
+	}
+
+	// История удалённого события доступна по префиксу ID.
+	if _, err := c.DeleteEvent(e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := c.History(e.ID[:8]); err != nil || len(list) != len(want)+1 {
+		t.Errorf("Ожидали %d записей истории удалённого события, получили %d, %v", len(want)+1, len(list), err)
+	}
+}
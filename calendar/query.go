@@ -0,0 +1,187 @@
+package calendar
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+type SortField string
+
+const (
+	SortByStart    SortField = "start"
+	SortByPriority SortField = "priority"
+	SortByTitle    SortField = "title"
+)
+
+type When string
+
+const (
+	WhenAny      When = ""
+	WhenPast     When = "past"
+	WhenUpcoming When = "upcoming"
+)
+
+var ErrInvalidSort = errors.New("неверное поле сортировки")
+
+func (f SortField) Validate() error {
+	switch f {
+	case "", SortByStart, SortByPriority, SortByTitle:
+		return nil
+	default:
+		return ErrInvalidSort
+	}
+}
+
+// Query описывает выборку вхождений событий. Нулевые поля не ограничивают выборку;
+// From и To задают интервал [From, To), с которым должно пересекаться вхождение.
+type Query struct {
+	From        time.Time
+	To          time.Time
+	Priority    events.Priority
+	Calendar    string
+	HasReminder bool
+	When        When
+
+	Sort SortField
+	Desc bool
+
+	Offset int
+	Limit  int
+}
+
+type QueryResult struct {
+	Occurrences []events.Occurrence
+	Total       int
+}
+
+// Query возвращает отфильтрованные, отсортированные и разбитые на страницы вхождения.
+// Повторяющиеся события разворачиваются в окне запроса; если окно не задано —
+// на config.RecurrenceListHorizon от текущего момента (в прошлое для WhenPast).
+// Total — число вхождений до применения Offset и Limit.
+func (c *Calendar) Query(q Query) QueryResult {
+	now := time.Now()
+	from, to := q.window(now)
+	fallbackNext := q.From.IsZero() && q.To.IsZero() && q.When == WhenAny
+
+	c.mu.RLock()
+	var list []events.Occurrence
+	for _, e := range c.calendarEvents {
+		if q.Priority != "" && e.Priority != q.Priority {
+			continue
+		}
+		if q.Calendar != "" && e.CalendarName() != q.Calendar {
+			continue
+		}
+		if q.HasReminder && len(e.Reminders) == 0 {
+			continue
+		}
+		e = e.Clone()
+		for _, o := range eventOccurrences(e, from, to, fallbackNext) {
+			if q.matches(o, now) {
+				list = append(list, o)
+			}
+		}
+	}
+	c.mu.RUnlock()
+
+	sortQuery(list, q.Sort, q.Desc)
+
+	result := QueryResult{Total: len(list)}
+	start := min(max(q.Offset, 0), len(list))
+	end := len(list)
+	if q.Limit > 0 {
+		end = min(start+q.Limit, len(list))
+	}
+	result.Occurrences = list[start:end]
+	return result
+}
+
+func (q Query) window(now time.Time) (time.Time, time.Time) {
+	from, to := q.From, q.To
+	if from.IsZero() {
+		if q.When == WhenPast {
+			from = now.Add(-config.RecurrenceListHorizon)
+		} else if to.IsZero() {
+			from = now
+		} else {
+			from = to.Add(-config.RecurrenceListHorizon)
+		}
+	}
+	if to.IsZero() {
+		if q.When == WhenPast {
+			to = now
+		} else {
+			to = from.Add(config.RecurrenceListHorizon)
+		}
+	}
+	return from, to
+}
+
+func (q Query) matches(o events.Occurrence, now time.Time) bool {
+	end := o.EndTime()
+	if end.Equal(o.StartAt) {
+		end = end.Add(time.Second)
+	}
+	if !q.From.IsZero() && !end.After(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !o.StartAt.Before(q.To) {
+		return false
+	}
+	switch q.When {
+	case WhenPast:
+		return !o.EndTime().After(now)
+	case WhenUpcoming:
+		return o.EndTime().After(now)
+	}
+	return true
+}
+
+// eventOccurrences возвращает единственное вхождение разового события или вхождения
+// повторяющегося события в [from, to); при fallbackNext пустое окно заменяется
+// ближайшим следующим вхождением.
+func eventOccurrences(e *events.Event, from, to time.Time, fallbackNext bool) []events.Occurrence {
+	if !e.IsRecurring() {
+		return e.Occurrences(e.StartAt, e.EndTime().Add(time.Second))
+	}
+	occurrences := e.Occurrences(from, to)
+	if len(occurrences) == 0 && fallbackNext {
+		if next, ok := e.NextOccurrence(from); ok {
+			occurrences = append(occurrences, next)
+		}
+	}
+	return occurrences
+}
+
+func sortQuery(list []events.Occurrence, field SortField, desc bool) {
+	compare := func(a, b events.Occurrence) int {
+		switch field {
+		case SortByPriority:
+			if d := b.Event.Priority.Rank() - a.Event.Priority.Rank(); d != 0 {
+				return d
+			}
+		case SortByTitle:
+			if d := strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)); d != 0 {
+				return d
+			}
+		}
+		if d := a.StartAt.Compare(b.StartAt); d != 0 {
+			return d
+		}
+		if d := strings.Compare(a.Title, b.Title); d != 0 {
+			return d
+		}
+		return strings.Compare(a.Event.ID, b.Event.ID)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if desc {
+			return compare(list[j], list[i]) < 0
+		}
+		return compare(list[i], list[j]) < 0
+	})
+}
@@ -0,0 +1,60 @@
+package calendar
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+func TestQuery(t *testing.T) {
+	c := newTestCalendar(t)
+	base := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	add := func(title string, offset time.Duration, p events.Priority) *events.Event {
+		t.Helper()
+		e, err := c.AddEvent(title, datetime.FormatLocal(base.Add(offset)), "", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	add("Бета", 2*time.Hour, events.PriorityLow)
+	add("Альфа", 3*time.Hour, events.PriorityHigh)
+	withReminder := add("Гамма", time.Hour, events.PriorityMedium)
+	add("Прошлое", -96*time.Hour, events.PriorityHigh)
+	if _, err := c.SetEventReminder(withReminder.ID, "Не забыть", "-30m"); err != nil {
+		t.Fatal(err)
+	}
+
+	titles := func(r QueryResult) []string {
+		var out []string
+		for _, o := range r.Occurrences {
+			out = append(out, o.Title)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		q     Query
+		want  []string
+		total int
+	}{
+		{"по началу", Query{}, []string{"Прошлое", "Гамма", "Бета", "Альфа"}, 4},
+		{"по приоритету", Query{Sort: SortByPriority}, []string{"Прошлое", "Альфа", "Гамма", "Бета"}, 4},
+		{"по названию обратно", Query{Sort: SortByTitle, Desc: true}, []string{"Прошлое", "Гамма", "Бета", "Альфа"}, 4},
+		{"предстоящие", Query{When: WhenUpcoming, Priority: events.PriorityHigh}, []string{"Альфа"}, 1},
+		{"прошедшие", Query{When: WhenPast}, []string{"Прошлое"}, 1},
+		{"с напоминанием", Query{HasReminder: true}, []string{"Гамма"}, 1},
+		{"интервал", Query{From: base.Add(90 * time.Minute), To: base.Add(3 * time.Hour)}, []string{"Бета"}, 1},
+		{"страница", Query{Offset: 1, Limit: 2}, []string{"Гамма", "Бета"}, 4},
+	}
+	for _, tt := range tests {
+		got := c.Query(tt.q)
+		if g := titles(got); !slices.Equal(g, tt.want) || got.Total != tt.total {
+			t.Errorf("%s: ожидали %v (всего %d), получили: %v (всего %d)", tt.name, tt.want, tt.total, g, got.Total)
+		}
+	}
+}
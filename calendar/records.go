@@ -0,0 +1,93 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+// saveRecords сохраняет в репозиторий только изменившиеся и удалённые события,
+// сравнивая их с последним сохранённым состоянием. Вызывается под saveMu.
+func (c *Calendar) saveRecords() error {
+	c.mu.RLock()
+	current, err := c.marshalEvents()
+	var changed []*events.Event
+	for id, data := range current {
+		if bytes.Equal(c.saved[id], data) {
+			continue
+		}
+		e, ok := c.calendarEvents[id]
+		if !ok {
+			e = c.trash[id]
+		}
+		changed = append(changed, e.Clone())
+	}
+	c.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	var deleted []string
+	for id := range c.saved {
+		if _, ok := current[id]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	if len(changed) == 0 && len(deleted) == 0 {
+		return nil
+	}
+
+	err = c.repo.Update(func(tx storage.Tx) error {
+		for _, e := range changed {
+			if err := tx.Put(e); err != nil {
+				return err
+			}
+		}
+		for _, id := range deleted {
+			if err := tx.Delete(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.saved = current
+	return nil
+}
+
+func (c *Calendar) loadRecords() error {
+	list, err := c.repo.List(storage.Filter{})
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки из стораджа: %w", err)
+	}
+
+	loaded := make(map[string]*events.Event, len(list))
+	saved := make(map[string][]byte, len(list))
+	for _, e := range list {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации JSON: %w", err)
+		}
+		loaded[e.ID] = e
+		saved[e.ID] = data
+	}
+
+	c.saveMu.Lock()
+	c.saved = saved
+	c.saveMu.Unlock()
+
+	c.mu.Lock()
+	c.setEvents(loaded)
+	missed := c.rearmReminders()
+	c.mu.Unlock()
+
+	if missed {
+		return c.Save()
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package calendar
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+// countingStore считает записи, переданные в ApplyRecords.
+type countingStore struct {
+	*storage.SqliteStorage
+	put, deleted int
+}
+
+func (s *countingStore) ApplyRecords(put []storage.Record, deleted []string) error {
+	s.put += len(put)
+	s.deleted += len(deleted)
+	return s.SqliteStorage.ApplyRecords(put, deleted)
+}
+
+func TestRecordStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.db")
+	db, err := storage.NewSqliteStorage(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &countingStore{SqliteStorage: db}
+
+	c := NewCalendar(s)
+	c.DisableReminders()
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	var ids []string
+	for i := range 3 {
+		e, err := c.AddEvent("Встреча", datetime.FormatLocal(base.Add(time.Duration(i)*24*time.Hour)), "1h", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if s.put != 3 {
+		t.Errorf("Ожидали 3 записи после трех добавлений, получили: %d", s.put)
+	}
+
+	if _, _, err := c.EditEvent(ids[1], "Созвон", "_", "_", "_"); err != nil {
+		t.Fatal(err)
+	}
+	// Удаление перекладывает событие в корзину, и запись только обновляется;
+	// из хранилища она удаляется при очистке корзины.
+	if _, err := c.DeleteEvent(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	if s.put != 5 || s.deleted != 0 {
+		t.Errorf("Ожидали 5 записей и 0 удалений, получили %d и %d", s.put, s.deleted)
+	}
+	if n := c.EmptyTrash(0); n != 1 || s.deleted != 1 {
+		t.Errorf("Ожидали одно удалённое из корзины событие и одно удаление записи, получили %d и %d", n, s.deleted)
+	}
+
+	records, err := db.RecordsInRange(base.Add(time.Hour), base.Add(72*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != ids[1] {
+		t.Errorf("Ожидали только запись %s в диапазоне, получили: %v", ids[1], records)
+	}
+
+	reloaded := NewCalendar(db)
+	reloaded.DisableReminders()
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := reloaded.GetEvent(ids[1])
+	if err != nil || e.Title != "Созвон" || len(reloaded.GetEvents()) != 2 {
+		t.Errorf("Ожидали событие Созвон среди двух событий после перезагрузки, получили: %v, %v (всего %d)", e, err, len(reloaded.GetEvents()))
+	}
+}
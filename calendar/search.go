@@ -0,0 +1,236 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+type SearchField string
+
+const (
+	SearchAny      SearchField = ""
+	SearchTitle    SearchField = "title"
+	SearchReminder SearchField = "reminder"
+	SearchPriority SearchField = "priority"
+)
+
+var (
+	ErrEmptySearchQuery   = errors.New("пустой поисковый запрос")
+	ErrInvalidSearchQuery = errors.New("неверный поисковый запрос")
+)
+
+// Веса совпадений для ранжирования результатов поиска.
+const (
+	scoreTitleExact    = 10
+	scoreTitleWord     = 5
+	scoreTitleSubstr   = 3
+	scoreReminderMatch = 1
+)
+
+type SearchTerm struct {
+	Field SearchField
+	Text  string
+}
+
+type SearchQuery struct {
+	Terms []SearchTerm
+}
+
+type SearchResult struct {
+	Event *events.Event
+	// Occurrence — вхождение, название которого подошло под запрос лучше названия серии,
+	// а иначе ближайшее вхождение события или, если предстоящих нет, последнее.
+	Occurrence events.Occurrence
+	Score      int
+}
+
+// NewSearchQuery собирает запрос из слов и фраз, уже разделённых с учётом кавычек,
+// с префиксами полей title:, reminder:, priority:.
+func NewSearchQuery(tokens []string) (SearchQuery, error) {
+	var q SearchQuery
+	for _, token := range tokens {
+		term := SearchTerm{Text: token}
+		if field, text, ok := strings.Cut(token, ":"); ok {
+			switch f := SearchField(strings.ToLower(field)); f {
+			case SearchTitle, SearchReminder, SearchPriority:
+				term = SearchTerm{Field: f, Text: text}
+			}
+		}
+
+		term.Text = normalizeSearchText(term.Text)
+		if term.Text == "" {
+			continue
+		}
+		if term.Field == SearchPriority {
+			if err := events.Priority(term.Text).Validate(); err != nil {
+				return SearchQuery{}, fmt.Errorf("%s: %w", token, err)
+			}
+		}
+		q.Terms = append(q.Terms, term)
+	}
+	if len(q.Terms) == 0 {
+		return SearchQuery{}, ErrEmptySearchQuery
+	}
+	return q, nil
+}
+
+// normalizeSearchText приводит текст к нижнему регистру, заменяет ё на е
+// и схлопывает пробелы.
+func normalizeSearchText(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, "ё", "е")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Search возвращает события, подходящие под все условия запроса, по убыванию
+// релевантности; при равной релевантности — по времени начала.
+func (c *Calendar) Search(q SearchQuery) []SearchResult {
+	now := time.Now()
+	c.mu.RLock()
+	var results []SearchResult
+	for _, e := range c.calendarEvents {
+		score, matched, ok := matchEvent(e, q, now)
+		if !ok {
+			continue
+		}
+		clone := e.Clone()
+		occurrence := searchOccurrence(clone, now)
+		if matched != nil {
+			occurrence = *matched
+			occurrence.Event = clone
+		}
+		results = append(results, SearchResult{Event: clone, Occurrence: occurrence, Score: score})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Occurrence.StartAt.Equal(b.Occurrence.StartAt) {
+			return a.Occurrence.StartAt.Before(b.Occurrence.StartAt)
+		}
+		return a.Event.ID < b.Event.ID
+	})
+	return results
+}
+
+// searchOccurrence выбирает вхождение, которое показывается в результатах поиска:
+// ближайшее предстоящее, а для закончившихся событий и серий — последнее.
+func searchOccurrence(e *events.Event, now time.Time) events.Occurrence {
+	if o, ok := e.NextOccurrence(now); ok {
+		return o
+	}
+	if past := e.Occurrences(e.StartAt, now); len(past) > 0 {
+		return past[len(past)-1]
+	}
+	return events.Occurrence{Event: e, Title: e.Title, StartAt: e.StartAt, EndAt: e.EndAt, RecurrenceID: e.StartAt}
+}
+
+// matchEvent оценивает событие по запросу. Условия на название проверяются и по названию
+// серии, и по названиям вхождений, переименованных исключениями; matched — вхождение,
+// которое подошло лучше серии, или nil.
+func matchEvent(e *events.Event, q SearchQuery, now time.Time) (score int, matched *events.Occurrence, ok bool) {
+	var messages []string
+	for _, r := range e.Reminders {
+		messages = append(messages, normalizeSearchText(r.Snapshot().Message))
+	}
+
+	score, ok = matchTerms(e, normalizeSearchText(e.Title), messages, q)
+	for _, o := range e.ExceptionOccurrences() {
+		if o.Title == e.Title {
+			continue
+		}
+		s, found := matchTerms(e, normalizeSearchText(o.Title), messages, q)
+		if !found || s < score || s == score && (matched == nil || !nearer(o, *matched, now)) {
+			continue
+		}
+		score, matched, ok = s, &o, true
+	}
+	return score, matched, ok
+}
+
+// nearer сообщает, ближе ли вхождение a к now, чем b: предстоящие идут раньше прошедших,
+// из предстоящих ближе более раннее, из прошедших — более позднее.
+func nearer(a, b events.Occurrence, now time.Time) bool {
+	aNext, bNext := !a.StartAt.Before(now), !b.StartAt.Before(now)
+	if aNext != bNext {
+		return aNext
+	}
+	if aNext {
+		return a.StartAt.Before(b.StartAt)
+	}
+	return a.StartAt.After(b.StartAt)
+}
+
+func matchTerms(e *events.Event, title string, messages []string, q SearchQuery) (int, bool) {
+	total := 0
+	for _, term := range q.Terms {
+		score := 0
+		switch term.Field {
+		case SearchPriority:
+			if string(e.Priority) == term.Text {
+				score = 1
+			}
+		case SearchTitle:
+			score = titleScore(title, term.Text)
+		case SearchReminder:
+			score = reminderScore(messages, term.Text)
+		default:
+			score = titleScore(title, term.Text) + reminderScore(messages, term.Text)
+		}
+		if score == 0 {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+func titleScore(title, text string) int {
+	switch {
+	case title == text:
+		return scoreTitleExact
+	case containsWord(title, text):
+		return scoreTitleWord
+	case strings.Contains(title, text):
+		return scoreTitleSubstr
+	default:
+		return 0
+	}
+}
+
+func reminderScore(messages []string, text string) int {
+	for _, m := range messages {
+		if strings.Contains(m, text) {
+			return scoreReminderMatch
+		}
+	}
+	return 0
+}
+
+// containsWord сообщает, встречается ли text в s с начала слова.
+func containsWord(s, text string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], text)
+		if j < 0 {
+			return false
+		}
+		j += i
+		if j == 0 {
+			return true
+		}
+		if r, _ := utf8.DecodeLastRuneInString(s[:j]); !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return true
+		}
+		i = j + 1
+	}
+}
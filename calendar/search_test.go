@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+func TestNewSearchQuery(t *testing.T) {
+	q, err := NewSearchQuery([]string{"Код  РЕВЬЮ", "priority:high", "title:план Ёлки", "data:x", "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchTerm{
+		{Text: "код ревью"},
+		{Field: SearchPriority, Text: "high"},
+		{Field: SearchTitle, Text: "план елки"},
+		{Text: "data:x"},
+	}
+	if !slices.Equal(q.Terms, want) {
+		t.Errorf("Ожидали условия %+v, получили: %+v", want, q.Terms)
+	}
+
+	if _, err := NewSearchQuery([]string{"title:"}); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Errorf("Ожидали ErrEmptySearchQuery, получили: %v", err)
+	}
+	if _, err := NewSearchQuery([]string{"priority:urgent"}); err == nil {
+		t.Error("Ожидали ошибку для неверного приоритета")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := newTestCalendar(t)
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	add := func(title string, p events.Priority) *events.Event {
+		t.Helper()
+		e, err := c.AddEvent(title, start, "", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	add("Ревью", events.PriorityLow)
+	add("Код ревью", events.PriorityHigh)
+	add("Превью релиза", events.PriorityLow)
+	party := add("Праздник", events.PriorityMedium)
+	if _, err := c.SetEventReminder(party.ID, "Купить ёлку", "-1h"); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(s string) []string {
+		t.Helper()
+		var titles []string
+		for _, r := range c.Search(mustParseSearch(t, s)) {
+			titles = append(titles, r.Event.Title)
+		}
+		return titles
+	}
+
+	if got, want := search("ревью"), []string{"Ревью", "Код ревью", "Превью релиза"}; !slices.Equal(got, want) {
+		t.Errorf("Ожидали %v по запросу ревью, получили: %v", want, got)
+	}
+	if got, want := search("ревью priority:high"), []string{"Код ревью"}; !slices.Equal(got, want) {
+		t.Errorf("Ожидали %v с фильтром по приоритету, получили: %v", want, got)
+	}
+	if got, want := search("елку"), []string{"Праздник"}; !slices.Equal(got, want) {
+		t.Errorf("Ожидали %v без различия ё и е, получили: %v", want, got)
+	}
+	if got := search("title:елку"); len(got) != 0 {
+		t.Errorf("Не ожидали совпадений только по названию, получили: %v", got)
+	}
+}
+
+func TestSearchShowsNextOccurrence(t *testing.T) {
+	c := newTestCalendar(t)
+	first := time.Now().AddDate(0, 0, -10).Truncate(time.Hour)
+	e, err := c.AddEvent("Зарядка", datetime.FormatLocal(first), "30m", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetEventRecurrence(e.ID, "FREQ=DAILY"); err != nil {
+		t.Fatal(err)
+	}
+
+	results := c.Search(mustParseSearch(t, "зарядка"))
+	if len(results) != 1 {
+		t.Fatalf("Ожидали один результат, получили: %v", results)
+	}
+	o := results[0].Occurrence
+	if o.StartAt.Before(time.Now()) || o.StartAt.After(time.Now().Add(24*time.Hour)) {
+		t.Errorf("Ожидали ближайшее повторение, получили: %s", datetime.FormatLocal(o.StartAt))
+	}
+	if o.EndAt.Sub(o.StartAt) != 30*time.Minute {
+		t.Errorf("Ожидали окончание через 30 минут, получили: %s", datetime.FormatLocal(o.EndAt))
+	}
+}
+
+func TestSearchExceptionTitles(t *testing.T) {
+	c := newTestCalendar(t)
+	first := time.Now().AddDate(0, 0, -10).Truncate(time.Hour)
+	e, err := c.AddEvent("Зарядка", datetime.FormatLocal(first), "30m", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetEventRecurrence(e.ID, "FREQ=DAILY"); err != nil {
+		t.Fatal(err)
+	}
+	renamed := first.AddDate(0, 0, 13)
+	if err := c.EditOccurrence(e.ID, datetime.FormatLocal(renamed), "Йога в парке", "_"); err != nil {
+		t.Fatal(err)
+	}
+
+	results := c.Search(mustParseSearch(t, "йога"))
+	if len(results) != 1 {
+		t.Fatalf("Ожидали один результат по названию вхождения, получили: %v", results)
+	}
+	if o := results[0].Occurrence; o.Title != "Йога в парке" || !o.StartAt.Equal(renamed) {
+		t.Errorf("Ожидали вхождение Йога в парке на %s, получили: %s на %s",
+			datetime.FormatLocal(renamed), o.Title, datetime.FormatLocal(o.StartAt))
+	}
+	if results[0].Score != scoreTitleWord {
+		t.Errorf("Ожидали оценку %d, получили: %d", scoreTitleWord, results[0].Score)
+	}
+
+	results = c.Search(mustParseSearch(t, "зарядка"))
+	if len(results) != 1 || results[0].Occurrence.Title != "Зарядка" {
+		t.Errorf("Ожидали ближайшее вхождение серии Зарядка, получили: %v", results)
+	}
+}
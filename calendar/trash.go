@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+var ErrEventExists = errors.New("событие с таким ID уже есть в календаре")
+
+// setEvents раскладывает загруженные события по календарю и корзине. Вызывающий
+// должен держать блокировку календаря.
+func (c *Calendar) setEvents(loaded map[string]*events.Event) {
+	c.calendarEvents = make(map[string]*events.Event, len(loaded))
+	c.trash = make(map[string]*events.Event)
+	for id, e := range loaded {
+		if e.DeletedAt.IsZero() {
+			c.calendarEvents[id] = e
+		} else {
+			c.trash[id] = e
+		}
+	}
+}
+
+func stopReminders(e *events.Event) {
+	for _, r := range e.Reminders {
+		r.Stop()
+	}
+}
+
+// moveToTrash останавливает напоминания события и перекладывает его в корзину;
+// сами напоминания сохраняются, чтобы вернуть их при восстановлении.
+func (c *Calendar) moveToTrash(e *events.Event, now time.Time) {
+	stopReminders(e)
+	e.DeletedAt = now.UTC()
+	delete(c.calendarEvents, e.ID)
+	c.trash[e.ID] = e
+}
+
+// Trash возвращает события в корзине, последние удалённые — первыми.
+func (c *Calendar) Trash() []*events.Event {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	list := make([]*events.Event, 0, len(c.trash))
+	for _, e := range c.trash {
+		list = append(list, e.Clone())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].DeletedAt.Equal(list[j].DeletedAt) {
+			return list[i].DeletedAt.After(list[j].DeletedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// TrashIDs — то же, что IDs, для событий в корзине.
+func (c *Calendar) TrashIDs(prefix string) []Candidate {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return candidatesIn(c.trash, prefix)
+}
+
+// RestoreEvent возвращает событие из корзины и заново запускает его напоминания,
+// которые ещё не наступили.
+func (c *Calendar) RestoreEvent(id string) (*events.Event, error) {
+	var restored *events.Event
+	err := c.mutate(OpRestore, func() error {
+		e, err := lookupIn(c.trash, id)
+		if err != nil {
+			return err
+		}
+		if _, exists := c.calendarEvents[e.ID]; exists {
+			return fmt.Errorf("id=%q: %w", e.ID, ErrEventExists)
+		}
+		c.track(e.ID)
+		delete(c.trash, e.ID)
+		e.DeletedAt = time.Time{}
+		c.calendarEvents[e.ID] = e
+		c.armReminders(e, time.Now())
+		restored = e.Clone()
+		logger.Info(fmt.Sprintf("Событие восстановлено из корзины: ID=%s, Title=%s", e.ID, e.Title))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// EmptyTrash окончательно удаляет события, которые лежат в корзине не меньше
+// olderThan; при olderThan = 0 — все. Возвращает число удалённых событий.
+func (c *Calendar) EmptyTrash(olderThan time.Duration) int {
+	removed := 0
+	_ = c.mutate(OpPurge, func() error {
+		cutoff := time.Now().Add(-olderThan)
+		for id, e := range c.trash {
+			if e.DeletedAt.After(cutoff) {
+				continue
+			}
+			c.track(id)
+			delete(c.trash, id)
+			removed++
+		}
+		return nil
+	})
+	logger.Info(fmt.Sprintf("Из корзины удалено событий: %d", removed))
+	return removed
+}
@@ -0,0 +1,101 @@
+package calendar
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestTrash(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	c := NewCalendar(storage.NewJsonStorage(filename))
+	t.Cleanup(func() {
+		c.StopReminders()
+		c.Close()
+	})
+
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour).Truncate(time.Hour))
+	add := func(title string) *events.Event {
+		e, err := c.AddEvent(title, start, "1h", events.PriorityLow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.SetEventReminder(e.ID, title, "2s"); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	deleted, restored := add("Удалена"), add("Восстановлена")
+	for _, e := range []*events.Event{deleted, restored} {
+		if _, err := c.DeleteEvent(e.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(c.GetEvents()); n != 0 {
+		t.Errorf("Ожидали 0 событий вне корзины, получили %d", n)
+	}
+	if res := c.Search(mustParseSearch(t, "удалена")); len(res) != 0 {
+		t.Errorf("Не ожидали удалённых событий в поиске, получили: %v", res)
+	}
+	if res := c.Query(Query{}); res.Total != 0 {
+		t.Errorf("Не ожидали удалённых событий в выборке, получили %d", res.Total)
+	}
+	if list := c.Trash(); len(list) != 2 || list[0].DeletedAt.IsZero() {
+		t.Fatalf("Ожидали два события в корзине с временем удаления, получили: %v", list)
+	}
+
+	reloaded := NewCalendar(storage.NewJsonStorage(filename))
+	reloaded.DisableReminders()
+	t.Cleanup(reloaded.Close)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Trash()) != 2 || len(reloaded.GetEvents()) != 0 {
+		t.Errorf("Ожидали после перезагрузки 2 события в корзине и 0 вне её, получили %d и %d", len(reloaded.Trash()), len(reloaded.GetEvents()))
+	}
+
+	e, err := c.RestoreEvent(restored.ID[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.DeletedAt.IsZero() || len(e.Reminders) != 1 {
+		t.Errorf("Ожидали восстановленное событие с напоминанием, получили: %+v", e)
+	}
+
+	// Напоминание восстановленного события срабатывает, удалённого — нет.
+	select {
+	case msg := <-c.Notification:
+		if !strings.Contains(msg, "Восстановлена") {
+			t.Errorf("Ожидали уведомление события Восстановлена, получили %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ожидали срабатывания напоминания восстановленного события, его не было")
+	}
+	select {
+	case msg := <-c.Notification:
+		t.Errorf("Не ожидали уведомления, получили %q", msg)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if n := c.EmptyTrash(time.Hour); n != 0 {
+		t.Errorf("Ожидали, что EmptyTrash(1h) ничего не удалит, удалено %d", n)
+	}
+	if n := c.EmptyTrash(0); n != 1 || len(c.Trash()) != 0 {
+		t.Errorf("Ожидали удаление одного события и пустую корзину, получили %d и %d", n, len(c.Trash()))
+	}
+}
+
+func mustParseSearch(t *testing.T, s string) SearchQuery {
+	t.Helper()
+	q, err := NewSearchQuery(strings.Fields(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
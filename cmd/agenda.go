@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const (
+	todayFormat = "today"
+	weekFormat  = "week [дата]"
+	monthFormat = "month [ГГГГ-ММ]"
+
+	markerEvents   = "•"
+	markerPriority = "!"
+)
+
+func (c *Cmd) handleToday(parts []string) {
+	logger.Info("Обработка команды today")
+	if len(parts) > 1 {
+		c.usage(todayFormat)
+		logger.Error("Неверный формат команды today")
+		return
+	}
+	day := datetime.StartOfDay(time.Now())
+	c.printAgenda(day, day.AddDate(0, 0, 1), true)
+}
+
+func (c *Cmd) handleWeek(parts []string) {
+	logger.Info("Обработка команды week")
+	if len(parts) > 2 {
+		c.usage(weekFormat)
+		logger.Error("Неверный формат команды week")
+		return
+	}
+	day := time.Now()
+	if len(parts) == 2 {
+		t, err := datetime.ParseLocalDate(parts[1])
+		if err != nil {
+			c.fail("Ошибка", fmt.Errorf("ошибка проверки даты: %w", events.ErrInvalidDate))
+			return
+		}
+		day = t
+	}
+	from := datetime.StartOfWeek(day)
+	c.printAgenda(from, from.AddDate(0, 0, 7), false)
+}
+
+func (c *Cmd) handleMonth(parts []string) {
+	logger.Info("Обработка команды month")
+	if len(parts) > 2 {
+		c.usage(monthFormat)
+		logger.Error("Неверный формат команды month")
+		return
+	}
+	month := time.Now()
+	if len(parts) == 2 {
+		t, err := datetime.ParseLocalMonth(parts[1])
+		if err != nil {
+			c.fail("Ошибка", fmt.Errorf("ошибка проверки месяца: %w", events.ErrInvalidDate))
+			return
+		}
+		month = t
+	}
+	from := datetime.StartOfMonth(month)
+	to := from.AddDate(0, 1, 0)
+	occurrences := c.calendar.Query(calendar.Query{From: from, To: to}).Occurrences
+
+	c.printMonthGrid(from, occurrences)
+	c.outputLn("")
+	c.printAgenda(from, to, false, occurrences...)
+}
+
+// printMonthGrid выводит сетку месяца с понедельника; дни с событиями помечаются
+// markerEvents, дни с событиями высокого приоритета — markerPriority.
+func (c *Cmd) printMonthGrid(from time.Time, occurrences []events.Occurrence) {
+	to := from.AddDate(0, 1, 0)
+	markers := make(map[int]string)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, o := range occurrencesOn(occurrences, day) {
+			if o.Event.Priority == events.PriorityHigh {
+				markers[day.Day()] = markerPriority
+				break
+			}
+			markers[day.Day()] = markerEvents
+		}
+	}
+
+	title := fmt.Sprintf("%s %d", datetime.MonthNames[from.Month()-1], from.Year())
+	width := 7*4 - 1
+	c.outputLn(strings.Repeat(" ", max(0, (width-len([]rune(title)))/2)) + title)
+	c.outputLn(" " + strings.Join(datetime.WeekdayShortNames[:], "  "))
+
+	var row strings.Builder
+	row.WriteString(strings.Repeat("    ", datetime.WeekdayIndex(from)))
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		marker := markers[day.Day()]
+		if marker == "" {
+			marker = " "
+		}
+		row.WriteString(fmt.Sprintf("%3d%s", day.Day(), marker))
+		if datetime.WeekdayIndex(day) == 6 {
+			c.outputLn(strings.TrimRight(row.String(), " "))
+			row.Reset()
+		}
+	}
+	if row.Len() > 0 {
+		c.outputLn(strings.TrimRight(row.String(), " "))
+	}
+	c.outputLn(fmt.Sprintf("%s — есть события, %s — есть события с высоким приоритетом", markerEvents, markerPriority))
+}
+
+// printAgenda выводит события по дням интервала [from, to). Если occurrences не переданы,
+// они запрашиваются у календаря. Пустые дни выводятся только для одного дня (single).
+func (c *Cmd) printAgenda(from, to time.Time, single bool, occurrences ...events.Occurrence) {
+	if occurrences == nil {
+		occurrences = c.calendar.Query(calendar.Query{From: from, To: to}).Occurrences
+	}
+
+	total := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		list := occurrencesOn(occurrences, day)
+		if len(list) == 0 && !single {
+			continue
+		}
+		c.outputLn(datetime.FormatDayHeader(day))
+		if len(list) == 0 {
+			c.outputLn("  Событий нет")
+		}
+		for _, o := range list {
+			c.outputLn(agendaLine(o, day))
+		}
+		total += len(list)
+	}
+	if total == 0 && !single {
+		c.outputLn("Событий нет")
+	}
+	logger.Info(fmt.Sprintf("Выведено %d событий с %s по %s", total, datetime.FormatLocalDate(from), datetime.FormatLocalDate(to)))
+}
+
+func occurrencesOn(occurrences []events.Occurrence, day time.Time) []events.Occurrence {
+	next := day.AddDate(0, 0, 1)
+	var list []events.Occurrence
+	for _, o := range occurrences {
+		end := o.EndTime()
+		if end.Equal(o.StartAt) {
+			end = end.Add(time.Second)
+		}
+		if o.StartAt.Before(next) && end.After(day) {
+			list = append(list, o)
+		}
+	}
+	return list
+}
+
+// agendaLine форматирует вхождение для дня day; события высокого приоритета
+// отмечаются markerPriority.
+func agendaLine(o events.Occurrence, day time.Time) string {
+	var when string
+	switch {
+	case o.Event.AllDay:
+		when = "весь день"
+	default:
+		start, end := "...", "..."
+		if !o.StartAt.Before(day) {
+			start = o.StartAt.In(time.Local).Format(datetime.TimeLayoutFormat)
+		}
+		switch {
+		case o.EndAt.IsZero():
+			end = ""
+		case o.EndAt.Before(day.AddDate(0, 0, 1)):
+			end = o.EndAt.In(time.Local).Format(datetime.TimeLayoutFormat)
+		}
+		when = start
+		if end != "" {
+			when += "-" + end
+		}
+	}
+
+	marker := " "
+	if o.Event.Priority == events.PriorityHigh {
+		marker = markerPriority
+	}
+	title := o.Title
+	if o.Event.IsRecurring() {
+		title += " ↻"
+	}
+	return fmt.Sprintf(" %s %-*s %s  [%s]", marker, config.AgendaTimeWidth, when, title, shortID(o.Event.ID))
+}
+
+func shortID(id string) string {
+	if len(id) <= config.AgendaIDWidth {
+		return id
+	}
+	return id[:config.AgendaIDWidth]
+}
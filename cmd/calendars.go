@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const (
+	calendarCreateFormat = "calendar-create <имя> [--color <цвет>] [--priority <приоритет>]"
+	calendarUseFormat    = "calendar-use <имя>"
+	moveFormat           = "move <ID> <календарь>"
+)
+
+func (c *Cmd) handleCalendars() {
+	logger.Info("Обработка команды calendars")
+	list := c.calendar.Calendars()
+	width := 0
+	for _, s := range list {
+		width = max(width, len([]rune(s.Name)))
+	}
+	for _, s := range list {
+		marker := " "
+		if s.Active {
+			marker = "*"
+		}
+		var details []string
+		if s.Color != "" {
+			details = append(details, "цвет "+s.Color)
+		}
+		if s.Priority != "" {
+			details = append(details, "приоритет "+string(s.Priority))
+		}
+		details = append(details, fmt.Sprintf("событий: %d", s.Events))
+		c.outputLn(fmt.Sprintf("%s %-*s  %s", marker, width, s.Name, strings.Join(details, ", ")))
+	}
+	logger.Info(fmt.Sprintf("Выведено календарей: %d", len(list)))
+}
+
+func (c *Cmd) handleCalendarCreate(parts []string) {
+	logger.Info("Обработка команды calendar-create")
+	if len(parts) < 2 || strings.HasPrefix(parts[1], "--") {
+		c.usage(calendarCreateFormat)
+		logger.Error("Неверный формат команды calendar-create")
+		return
+	}
+	var color string
+	var priority events.Priority
+	for i := 2; i < len(parts); i += 2 {
+		if i+1 >= len(parts) {
+			c.usage(calendarCreateFormat)
+			logger.Error("Неверный формат команды calendar-create")
+			return
+		}
+		switch parts[i] {
+		case "--color":
+			color = parts[i+1]
+		case "--priority":
+			priority = events.Priority(parts[i+1])
+		default:
+			c.usage(calendarCreateFormat)
+			logger.Error("Неверный формат команды calendar-create")
+			return
+		}
+	}
+
+	cal, err := c.calendar.CreateCalendar(parts[1], color, priority)
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка создания календаря: " + err.Error())
+		return
+	}
+	c.outputLn(fmt.Sprintf("Календарь %s создан", cal.Name))
+}
+
+func (c *Cmd) handleCalendarUse(parts []string) {
+	logger.Info("Обработка команды calendar-use")
+	if len(parts) != 2 {
+		c.usage(calendarUseFormat)
+		logger.Error("Неверный формат команды calendar-use")
+		return
+	}
+	cal, err := c.calendar.UseCalendar(parts[1])
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка выбора календаря: " + err.Error())
+		return
+	}
+	c.outputLn(fmt.Sprintf("Новые события добавляются в календарь %s", cal.Name))
+}
+
+func (c *Cmd) handleMove(parts []string) {
+	logger.Info("Обработка команды move")
+	if len(parts) != 3 {
+		c.usage(moveFormat)
+		logger.Error("Неверный формат команды move")
+		return
+	}
+	e, err := c.calendar.MoveEvent(parts[1], parts[2])
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка переноса события: " + err.Error())
+		return
+	}
+	c.outputLn(fmt.Sprintf("Событие \"%s\" в календаре %s", e.Title, e.CalendarName()))
+	logger.Info(fmt.Sprintf("Событие перенесено: ID=%s, календарь %s", e.ID, e.CalendarName()))
+}
+
+// calendarArg извлекает из аргументов команды параметр --calendar <имя> и
+// возвращает остальные аргументы.
+func calendarArg(parts []string) ([]string, string, error) {
+	rest := make([]string, 0, len(parts))
+	name := ""
+	for i := 0; i < len(parts); i++ {
+		if parts[i] != "--calendar" {
+			rest = append(rest, parts[i])
+			continue
+		}
+		if i+1 >= len(parts) {
+			return nil, "", fmt.Errorf("--calendar: нет значения")
+		}
+		i++
+		name = parts[i]
+	}
+	return rest, name, nil
+}
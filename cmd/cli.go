@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+	"github.com/leksusdev/calendarOfEvents/reminder"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitInvalid  = 3
+	exitNotFound = 4
+	exitConflict = 5
+)
+
+var errUnknownCommand = errors.New("неизвестная команда")
+
+type usageError struct {
+	format string
+	cause  error
+}
+
+func (e *usageError) Error() string {
+	if e.cause != nil {
+		return e.cause.Error()
+	}
+	return e.format
+}
+
+func (e *usageError) Unwrap() error {
+	return e.cause
+}
+
+var invalidArgumentErrors = []error{
+	events.ErrInvalidTitle,
+	events.ErrInvalidDate,
+	events.ErrInvalidEnd,
+	events.ErrZeroDuration,
+	events.ErrEmptyReminderTime,
+	events.ErrNoUpcomingOccurrence,
+	events.ErrInvalidPriority,
+	events.ErrInvalidCalendarName,
+	events.ErrInvalidColor,
+	events.ErrInvalidRecurrence,
+	events.ErrUnsupportedRecurrence,
+	events.ErrNotRecurring,
+	calendar.ErrInvalidSort,
+	calendar.ErrEmptySearchQuery,
+	calendar.ErrInvalidSearchQuery,
+	calendar.ErrInvalidSlotDuration,
+	calendar.ErrInvalidWorkHours,
+	storage.ErrWrongPassphrase,
+	storage.ErrEmptyPassphrase,
+	storage.ErrNotEncrypted,
+	errPassphraseMismatch,
+	reminder.ErrEmptyMessage,
+	reminder.ErrMessageTooLong,
+	reminder.ErrZeroTime,
+	reminder.ErrPastTime,
+}
+
+var notFoundErrors = []error{
+	calendar.ErrEventNotFound,
+	calendar.ErrReminderNotFound,
+	calendar.ErrNoFreeSlot,
+	events.ErrOccurrenceNotFound,
+	calendar.ErrNothingToUndo,
+	calendar.ErrNothingToRedo,
+	config.ErrUnknownSetting,
+	calendar.ErrCalendarNotFound,
+}
+
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyError возвращает машиночитаемый код ошибки и код завершения процесса.
+func classifyError(err error) (string, int) {
+	var usage *usageError
+	switch {
+	case errors.As(err, &usage):
+		return "usage", exitUsage
+	case errors.Is(err, errUnknownCommand):
+		return "unknown_command", exitUsage
+	case errors.Is(err, calendar.ErrAmbiguousID):
+		return "ambiguous_id", exitInvalid
+	case errors.Is(err, calendar.ErrConflict), errors.Is(err, calendar.ErrEventExists),
+		errors.Is(err, calendar.ErrCalendarExists):
+		return "conflict", exitConflict
+	case isAny(err, notFoundErrors):
+		return "not_found", exitNotFound
+	case isAny(err, invalidArgumentErrors):
+		return "invalid_argument", exitInvalid
+	default:
+		return "failed", exitError
+	}
+}
+
+type errorJSON struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// WriteError выводит ошибку одной строкой JSON и возвращает код завершения для неё.
+func WriteError(w io.Writer, err error) int {
+	var out errorJSON
+	code, exitCode := classifyError(err)
+	out.Error.Code = code
+	out.Error.Message = err.Error()
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(out)
+	return exitCode
+}
+
+// Exec выполняет одну команду без интерактивной оболочки теми же обработчиками,
+// что и executor, сохраняет данные и возвращает код завершения. Ошибка выводится
+// в stderr одной строкой JSON.
+func (c *Cmd) Exec(args []string) int {
+	c.batch = true
+	logger.Info(fmt.Sprintf("Неинтерактивный запуск: %v", args))
+
+	var err error
+	if len(args) == 0 {
+		err = &usageError{format: "calendar <команда> [аргументы]"}
+	} else {
+		c.dispatch(args)
+		err = c.lastErr
+	}
+
+	if c.shutdown() != exitOK && err == nil {
+		err = c.lastErr
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("Команда завершилась с ошибкой: %v", err))
+		return WriteError(os.Stderr, err)
+	}
+	return exitOK
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestExecExitCodes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"add", "Встреча", start, "1h", "high"}, exitOK},
+		{[]string{"list", "--json"}, exitOK},
+		{[]string{"add", "Встреча"}, exitUsage},
+		{[]string{"add", "Встреча", "завтра", "low"}, exitInvalid},
+		{[]string{"remove", "нет-такого"}, exitNotFound},
+		{[]string{"free", "1h", "--book", "Созвон"}, exitOK},
+		{[]string{"free", "1h", "--work-hours", "18-10"}, exitInvalid},
+		{[]string{"free", "2d"}, exitNotFound},
+		{[]string{"calendar-create", "work", "--color", "red"}, exitOK},
+		{[]string{"calendar-create", "work", "--shade", "red"}, exitUsage},
+		{[]string{"calendar-create", "work", "--color", "purple"}, exitInvalid},
+		{[]string{"calendar-use", "nope"}, exitNotFound},
+		{[]string{"list", "--calendar", "nope"}, exitNotFound},
+		{[]string{"move", "нет-такого", "default"}, exitNotFound},
+		{[]string{"config", "list.page_size"}, exitOK},
+		{[]string{"config", "list.height"}, exitNotFound},
+		{[]string{"frob"}, exitUsage},
+	}
+	for _, tt := range tests {
+		cal := calendar.NewCalendar(storage.NewJsonStorage(filename))
+		cal.DisableReminders()
+		if err := cal.Load(); err != nil {
+			t.Fatal(err)
+		}
+		c := NewCmd(cal)
+		c.out = io.Discard
+		if got := c.Exec(tt.args); got != tt.want {
+			t.Errorf("Ожидали код выхода %d для %q, получили: %d", tt.want, tt.args, got)
+		}
+	}
+
+	cal := calendar.NewCalendar(storage.NewJsonStorage(filename))
+	if err := cal.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(cal.GetEvents()); n != 2 {
+		t.Errorf("Ожидали 2 сохраненных события, получили: %d", n)
+	}
+}
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 
@@ -8,23 +11,57 @@ import (
 	"github.com/google/shlex"
 	"github.com/leksusdev/calendarOfEvents/calendar"
 	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/logger"
 )
 
 type Cmd struct {
 	calendar   *calendar.Calendar
 	wg         sync.WaitGroup
 	logHandler *LogHandler
+	out        io.Writer
+
+	// readPassphrase запрашивает пароль без эха; в тестах подменяется.
+	readPassphrase func(prompt string) (string, error)
+
+	// batch включается в неинтерактивном режиме: ошибки не печатаются,
+	// а возвращаются вызывающему в lastErr.
+	batch       bool
+	lastErr     error
+	sourceDepth int
+
+	// missedReported — сколько пропущенных напоминаний уже выведено.
+	missedReported int
+
+	// closer — хранилище календаря; закрывается в shutdown, в том числе при
+	// завершении по сигналу, когда до возврата из main дело не доходит.
+	closer io.Closer
+	// exit завершает процесс после сигнала; в тестах подменяется.
+	exit func(code int)
+
+	exiting      bool
+	signalOnce   sync.Once
+	shutdownOnce sync.Once
+	exitCode     int
 }
 
 func NewCmd(c *calendar.Calendar) *Cmd {
 	return &Cmd{
-		calendar:   c,
-		logHandler: NewLogHandler(),
+		calendar:       c,
+		logHandler:     NewLogHandler(),
+		out:            os.Stdout,
+		readPassphrase: ReadPassphrase,
+		exit:           os.Exit,
 	}
 }
 
+// SetCloser задаёт хранилище, которое закрывается при завершении работы после
+// сохранения данных.
+func (c *Cmd) SetCloser(closer io.Closer) {
+	c.closer = closer
+}
+
 func (c *Cmd) output(s string) {
-	print(s)
+	fmt.Fprint(c.out, s)
 	c.logHandler.AddLine(s)
 }
 
@@ -41,20 +78,37 @@ func (c *Cmd) executor(input string) {
 
 	parts, err := shlex.Split(input)
 	if err != nil {
-		c.outputLn(err.Error())
+		c.fail("Ошибка", err)
 		return
 	}
 	if len(parts) == 0 {
 		return
 	}
+	c.dispatch(parts)
+	c.reportMissedReminders("Пропущено напоминаний")
+}
 
+func (c *Cmd) dispatch(parts []string) {
+	c.lastErr = nil
 	cmd := strings.ToLower(parts[0])
 
 	switch cmd {
 	case "add":
 		c.handleAdd(parts)
 	case "list":
-		c.handleList()
+		c.handleList(parts)
+	case "search":
+		c.handleSearch(parts)
+	case "conflicts":
+		c.handleConflicts(parts)
+	case "free":
+		c.handleFree(parts)
+	case "today":
+		c.handleToday(parts)
+	case "week":
+		c.handleWeek(parts)
+	case "month":
+		c.handleMonth(parts)
 	case "remove":
 		c.handleRemove(parts)
 	case "update":
@@ -63,8 +117,48 @@ func (c *Cmd) executor(input string) {
 		c.handleRemind(parts)
 	case "remind-cancel":
 		c.handleRemindCancel(parts)
+	case "reminders":
+		c.handleReminders(parts)
+	case "repeat":
+		c.handleRepeat(parts)
+	case "repeat-cancel":
+		c.handleRepeatCancel(parts)
+	case "repeat-skip":
+		c.handleRepeatSkip(parts)
+	case "repeat-edit":
+		c.handleRepeatEdit(parts)
+	case "export-ics":
+		c.handleExportICS(parts)
+	case "import-ics":
+		c.handleImportICS(parts)
+	case "source":
+		c.handleSource(parts)
 	case "help":
 		c.handleHelp()
+	case "trash":
+		c.handleTrash()
+	case "restore":
+		c.handleRestore(parts)
+	case "trash-empty":
+		c.handleTrashEmpty(parts)
+	case "undo":
+		c.handleUndo()
+	case "redo":
+		c.handleRedo()
+	case "history":
+		c.handleHistory(parts)
+	case "calendars":
+		c.handleCalendars()
+	case "calendar-create":
+		c.handleCalendarCreate(parts)
+	case "calendar-use":
+		c.handleCalendarUse(parts)
+	case "move":
+		c.handleMove(parts)
+	case "passwd":
+		c.handlePasswd()
+	case "config":
+		c.handleConfig(parts)
 	case "log":
 		c.handleLog()
 	case "log-save":
@@ -74,23 +168,64 @@ func (c *Cmd) executor(input string) {
 	case "exit":
 		c.handleExit()
 	default:
-		c.outputLn("Неизвестная команда")
-		c.outputLn("Введите <help> для информации")
+		c.lastErr = fmt.Errorf("%q: %w", parts[0], errUnknownCommand)
+		if !c.batch {
+			c.outputLn("Неизвестная команда")
+			c.outputLn("Введите <help> для информации")
+		}
+	}
+}
+
+// fail выводит ошибку команды и запоминает её для кода завершения.
+func (c *Cmd) fail(prefix string, err error) {
+	c.lastErr = err
+	if !c.batch {
+		c.outputLn(prefix + ": " + err.Error())
 	}
 }
 
+func (c *Cmd) usage(format string) {
+	c.fail("Формат", &usageError{format: format})
+}
+
 func (c *Cmd) completer(d prompt.Document) []prompt.Suggest {
-	if strings.Contains(d.TextBeforeCursor(), " ") {
-		return []prompt.Suggest{}
+	if args, word := splitInput(d.TextBeforeCursor()); len(args) > 0 {
+		return c.argumentSuggestions(args, word)
 	}
 	suggestions := []prompt.Suggest{
 		{Text: "add", Description: "Добавить событие"},
 		{Text: "list", Description: "Показать все события"},
+		{Text: "search", Description: "Найти события"},
+		{Text: "conflicts", Description: "Показать пересечения событий"},
+		{Text: "free", Description: "Найти свободное время"},
+		{Text: "today", Description: "События на сегодня"},
+		{Text: "week", Description: "События на неделю"},
+		{Text: "month", Description: "Календарь на месяц"},
 		{Text: "update", Description: "Обновить событие"},
 		{Text: "remove", Description: "Удалить событие"},
 		{Text: "remind", Description: "Добавить напоминание к событию"},
 		{Text: "remind-cancel", Description: "Отменить напоминание к событию"},
+		{Text: "reminders", Description: "Показать напоминания события"},
+		{Text: "repeat", Description: "Сделать событие повторяющимся"},
+		{Text: "repeat-cancel", Description: "Отменить повторение события"},
+		{Text: "repeat-skip", Description: "Пропустить вхождение повторяющегося события"},
+		{Text: "repeat-edit", Description: "Изменить вхождение повторяющегося события"},
+		{Text: "export-ics", Description: "Экспортировать события в iCalendar"},
+		{Text: "import-ics", Description: "Импортировать события из iCalendar"},
+		{Text: "source", Description: "Выполнить команды из файла"},
 		{Text: "help", Description: "Описание команд"},
+		{Text: "trash", Description: "Показать корзину"},
+		{Text: "restore", Description: "Восстановить событие из корзины"},
+		{Text: "trash-empty", Description: "Очистить корзину"},
+		{Text: "undo", Description: "Отменить последнее изменение"},
+		{Text: "redo", Description: "Повторить отменённое изменение"},
+		{Text: "history", Description: "История изменений"},
+		{Text: "calendars", Description: "Показать календари"},
+		{Text: "calendar-create", Description: "Создать календарь"},
+		{Text: "calendar-use", Description: "Выбрать календарь для новых событий"},
+		{Text: "move", Description: "Перенести событие в другой календарь"},
+		{Text: "passwd", Description: "Сменить пароль зашифрованного календаря"},
+		{Text: "config", Description: "Показать настройки"},
 		{Text: "log", Description: "Показать лог сессии"},
 		{Text: "log-save", Description: "Сохранить лог в файл"},
 		{Text: "log-load", Description: "Загрузить лог из файла"},
@@ -100,17 +235,51 @@ func (c *Cmd) completer(d prompt.Document) []prompt.Suggest {
 	return prompt.FilterHasPrefix(suggestions, d.GetWordBeforeCursor(), true)
 }
 
-func (c *Cmd) Run() {
+func (c *Cmd) reportLoadWarnings() {
+	for _, w := range c.calendar.LoadWarnings() {
+		c.outputLn(w)
+	}
+}
+
+// reportMissedReminders выводит пропущенные напоминания, о которых ещё не сообщалось:
+// при запуске — найденные при загрузке, после команды — найденные при импорте,
+// восстановлении или переносе событий.
+func (c *Cmd) reportMissedReminders(title string) {
+	missed := c.calendar.MissedReminders()
+	if len(missed) <= c.missedReported {
+		return
+	}
+	missed = missed[c.missedReported:]
+	c.missedReported += len(missed)
+	c.outputLn(fmt.Sprintf("%s: %d", title, len(missed)))
+	for _, m := range missed {
+		c.outputLn("  " + m)
+	}
+}
+
+// Run запускает командную оболочку и возвращает код завершения процесса после
+// команды exit или Ctrl+D.
+func (c *Cmd) Run() int {
 	p := prompt.New(
 		c.executor,
 		c.completer,
 		prompt.OptionPrefix(config.PromptPrefix),
-		prompt.OptionMaxSuggestion(config.PromptMaxSuggestions),
+		prompt.OptionMaxSuggestion(uint16(config.PromptMaxSuggestions)),
+		prompt.OptionParser(newSignalParser(c.stopOnSignal)),
+		prompt.OptionSetExitCheckerOnInput(c.exitChecker),
 	)
+	c.handleSignals()
 	c.wg.Go(func() {
 		for msg := range c.calendar.Notification {
 			c.outputLn(msg)
 		}
 	})
+	c.reportLoadWarnings()
+	c.reportMissedReminders("Пропущено напоминаний за время отсутствия")
 	p.Run()
+
+	if !c.exiting {
+		logger.Info("Ввод завершён (Ctrl+D)")
+	}
+	return c.shutdown()
 }
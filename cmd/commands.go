@@ -1,76 +1,335 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/leksusdev/calendarOfEvents/calendar"
 	"github.com/leksusdev/calendarOfEvents/config"
 	"github.com/leksusdev/calendarOfEvents/datetime"
 	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/ical"
 	"github.com/leksusdev/calendarOfEvents/logger"
 )
 
 const (
-	addFormat          = "add <\"название события\"> <\"дата и время\"> <приоритет>"
+	addFormat          = "add <\"название события\"> <\"дата и время\"|\"дата\"> [окончание|duration] <приоритет> [--calendar <имя>]"
+	listFormat         = "list [фильтры] [--calendar <имя>] [--sort start|priority|title] [--desc] [--page N] [--limit N] [--json]"
 	removeFormat       = "remove <ID>"
-	updateFormat       = "update <ID> <\"название события\"> <\"дата и время\"> <приоритет>"
-	remindFormat       = "remind <ID> <\"сообщение\"> <\"дата и время\"|duration>"
-	cancelRemindFormat = "remind-cancel <ID>"
+	searchFormat       = "search <запрос> (слова, \"фраза\", title:, reminder:, priority:)"
+	updateFormat       = "update <ID> <\"название события\"> <\"дата и время\"|\"дата\"> [окончание|duration] <приоритет>"
+	remindFormat       = "remind <ID> <\"сообщение\"> <\"дата и время\"|duration|-duration>"
+	cancelRemindFormat = "remind-cancel <ID> [ID напоминания]"
+	remindersFormat    = "reminders <ID>"
+	repeatFormat       = "repeat <ID> <\"FREQ=WEEKLY;BYDAY=MO\">"
+	cancelRepeatFormat = "repeat-cancel <ID>"
+	skipRepeatFormat   = "repeat-skip <ID> <\"дата и время вхождения\">"
+	exportICSFormat    = "export-ics <файл>"
+	importICSFormat    = "import-ics <файл>"
+	editRepeatFormat   = "repeat-edit <ID> <\"дата и время вхождения\"> <\"название события\"> <\"дата и время\">"
+
+	helpNameWidth   = 20
+	helpFormatWidth = 96
 )
 
 func (c *Cmd) handleAdd(parts []string) {
 	logger.Info("Обработка команды add")
-	if len(parts) < 4 {
-		c.outputLn("Формат: " + addFormat)
+	parts, calendarName, err := calendarArg(parts)
+	if err != nil {
+		c.fail("Ошибка", &usageError{format: addFormat, cause: err})
+		if !c.batch {
+			c.outputLn("Формат: " + addFormat)
+		}
+		logger.Error("Неверный формат команды add: " + err.Error())
+		return
+	}
+	if len(parts) < 3 {
+		c.usage(addFormat)
 		logger.Error("Неверный формат команды add")
 		return
 	}
 
+	// Приоритет можно опустить, если он задан у календаря; тогда единственный
+	// необязательный аргумент — окончание события.
 	title := parts[1]
 	date := parts[2]
-	priority := events.Priority(parts[3])
+	end := ""
+	var priority events.Priority
+	switch {
+	case len(parts) > 4:
+		end = parts[3]
+		priority = events.Priority(parts[4])
+	case len(parts) == 4 && events.Priority(parts[3]).Validate() == nil:
+		priority = events.Priority(parts[3])
+	case len(parts) == 4:
+		end = parts[3]
+	}
 
-	e, err := c.calendar.AddEvent(title, date, priority)
+	e, err := c.calendar.AddEventTo(calendarName, title, date, end, priority)
 	if err != nil {
-		c.outputLn("Ошибка: " + err.Error())
+		c.fail("Ошибка", err)
 		logger.Error("Ошибка добавления события: " + err.Error())
 		return
 	}
-	c.outputLn("Событие: \"" + e.Title + "\" добавлено")
+	c.outputLn("Событие: \"" + e.Title + "\" добавлено, ID: " + e.ID)
 	logger.Info(fmt.Sprintf("Событие добавлено: ID=%s, Title=%s", e.ID, e.Title))
+	c.warnConflicts(e.ID)
+}
+
+type occurrenceJSON struct {
+	ID        string          `json:"id"`
+	Title     string          `json:"title"`
+	StartAt   time.Time       `json:"start_at"`
+	EndAt     time.Time       `json:"end_at,omitzero"`
+	AllDay    bool            `json:"all_day,omitempty"`
+	Priority  events.Priority `json:"priority"`
+	Calendar  string          `json:"calendar"`
+	Recurring bool            `json:"recurring,omitempty"`
+}
+
+type listOptions struct {
+	query  calendar.Query
+	asJSON bool
+	paged  bool
+	page   int
+}
+
+func parseListArgs(args []string) (listOptions, error) {
+	opts := listOptions{page: 1}
+	q := &opts.query
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s: нет значения", flag)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var v string
+		var err error
+		switch flag {
+		case "--json":
+			opts.asJSON = true
+		case "--has-reminder":
+			q.HasReminder = true
+		case "--past":
+			q.When = calendar.WhenPast
+		case "--upcoming":
+			q.When = calendar.WhenUpcoming
+		case "--desc":
+			q.Desc = true
+		case "--from", "--to":
+			if v, err = value(); err != nil {
+				return opts, err
+			}
+			t, err := parseBound(v, flag == "--to")
+			if err != nil {
+				return opts, fmt.Errorf("%s: %w", flag, err)
+			}
+			if flag == "--from" {
+				q.From = t
+			} else {
+				q.To = t
+			}
+		case "--calendar":
+			if v, err = value(); err != nil {
+				return opts, err
+			}
+			q.Calendar = events.NormalizeCalendarName(v)
+		case "--priority":
+			if v, err = value(); err != nil {
+				return opts, err
+			}
+			q.Priority = events.Priority(v)
+			if err := q.Priority.Validate(); err != nil {
+				return opts, err
+			}
+		case "--sort":
+			if v, err = value(); err != nil {
+				return opts, err
+			}
+			q.Sort = calendar.SortField(v)
+			if err := q.Sort.Validate(); err != nil {
+				return opts, err
+			}
+		case "--page", "--limit":
+			if v, err = value(); err != nil {
+				return opts, err
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return opts, fmt.Errorf("%s: ожидается положительное число", flag)
+			}
+			opts.paged = true
+			if flag == "--page" {
+				opts.page = n
+			} else {
+				q.Limit = n
+			}
+		default:
+			return opts, fmt.Errorf("%s: неизвестный параметр", flag)
+		}
+	}
+
+	if opts.paged {
+		if q.Limit == 0 {
+			q.Limit = config.ListPageSize
+		}
+		q.Offset = (opts.page - 1) * q.Limit
+	}
+	return opts, nil
 }
 
-func (c *Cmd) handleList() {
+// parseBound разбирает границу интервала; дата без времени в качестве верхней
+// границы включает весь день.
+func parseBound(s string, upper bool) (time.Time, error) {
+	if t, err := datetime.ParseLocal(s); err == nil {
+		return t, nil
+	}
+	t, err := datetime.ParseLocalDate(s)
+	if err != nil {
+		return time.Time{}, events.ErrInvalidDate
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func (c *Cmd) handleList(parts []string) {
 	logger.Info("Обработка команды list")
-	eventsList := c.calendar.GetEvents()
-	if len(eventsList) == 0 {
-		c.outputLn("Календарь пуст")
-		logger.Info("Календарь пуст")
+	opts, err := parseListArgs(parts[1:])
+	if err != nil {
+		c.fail("Ошибка", &usageError{format: listFormat, cause: err})
+		if !c.batch {
+			c.outputLn("Формат: " + listFormat)
+		}
+		logger.Error("Неверный формат команды list: " + err.Error())
 		return
 	}
 
-	c.outputLn(fmt.Sprintf("|%-*s|%-*s|%-*s|%-*s",
+	if opts.query.Calendar != "" {
+		if _, err := c.calendar.GetCalendar(opts.query.Calendar); err != nil {
+			c.fail("Ошибка", err)
+			logger.Error("Ошибка фильтра list: " + err.Error())
+			return
+		}
+	}
+
+	result := c.calendar.Query(opts.query)
+	if opts.asJSON {
+		c.listJSON(result.Occurrences)
+		return
+	}
+	if result.Total == 0 {
+		c.outputLn("Событий не найдено")
+		logger.Info("Событий не найдено")
+		return
+	}
+
+	c.printOccurrences(result.Occurrences)
+	if opts.paged {
+		pages := (result.Total + opts.query.Limit - 1) / opts.query.Limit
+		c.outputLn(fmt.Sprintf("Страница %d из %d, всего событий: %d", opts.page, pages, result.Total))
+	}
+	logger.Info(fmt.Sprintf("Выведено %d событий из %d", len(result.Occurrences), result.Total))
+}
+
+// printOccurrences выводит таблицу вхождений; колонка календаря появляется, когда
+// календарей больше одного.
+func (c *Cmd) printOccurrences(occurrences []events.Occurrence) {
+	withCalendar := len(c.calendar.Calendars()) > 1
+	header := fmt.Sprintf("|%-*s|%-*s|%-*s|%-*s",
 		config.ListColWidthID, "ID:",
 		config.ListColWidthTitle, "Событие:",
 		config.ListColWidthDate, "Дата-время:",
-		config.ListColWidthStatus, "Статус:"))
-	for _, e := range eventsList {
-		c.outputLn(fmt.Sprintf("|%-*s|%-*s|%-*s|%-*s",
-			config.ListColWidthID, e.ID,
-			config.ListColWidthTitle, e.Title+strings.Repeat(".", config.ListTitlePad-utf8.RuneCountInString(e.Title)),
-			config.ListColWidthDate, datetime.FormatLocal(e.StartAt),
-			config.ListColWidthStatus, e.Priority,
-		))
+		config.ListColWidthStatus, "Статус:")
+	if withCalendar {
+		header += fmt.Sprintf("|%-*s", config.ListColWidthCalendar, "Календарь:")
+	}
+	c.outputLn(header)
+	for _, o := range occurrences {
+		date := datetime.FormatRange(o.StartAt, o.EndAt, o.Event.AllDay)
+		if o.Event.IsRecurring() {
+			date += " ↻"
+		}
+		row := fmt.Sprintf("|%-*s|%-*s|%-*s|%-*s",
+			config.ListColWidthID, o.Event.ID,
+			config.ListColWidthTitle, o.Title+strings.Repeat(".", max(0, config.ListTitlePad-utf8.RuneCountInString(o.Title))),
+			config.ListColWidthDate, date,
+			config.ListColWidthStatus, o.Event.Priority,
+		)
+		if withCalendar {
+			row += fmt.Sprintf("|%-*s", config.ListColWidthCalendar, o.Event.CalendarName())
+		}
+		c.outputLn(row)
+	}
+}
+
+func (c *Cmd) listJSON(occurrences []events.Occurrence) {
+	list := make([]occurrenceJSON, 0, len(occurrences))
+	for _, o := range occurrences {
+		list = append(list, occurrenceJSON{
+			ID:        o.Event.ID,
+			Title:     o.Title,
+			StartAt:   o.StartAt,
+			EndAt:     o.EndAt,
+			AllDay:    o.Event.AllDay,
+			Priority:  o.Event.Priority,
+			Calendar:  o.Event.CalendarName(),
+			Recurring: o.Event.IsRecurring(),
+		})
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		c.fail("Ошибка", err)
+		return
+	}
+	c.outputLn(string(data))
+	logger.Info(fmt.Sprintf("Выведено %d событий в JSON", len(list)))
+}
+
+func (c *Cmd) handleSearch(parts []string) {
+	logger.Info("Обработка команды search")
+	if len(parts) < 2 {
+		c.usage(searchFormat)
+		logger.Error("Неверный формат команды search")
+		return
+	}
+
+	q, err := calendar.NewSearchQuery(parts[1:])
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка поискового запроса: " + err.Error())
+		return
 	}
-	logger.Info(fmt.Sprintf("Выведено %d событий", len(eventsList)))
+	results := c.calendar.Search(q)
+	if len(results) == 0 {
+		c.outputLn("Ничего не найдено")
+		logger.Info("Поиск: ничего не найдено")
+		return
+	}
+
+	occurrences := make([]events.Occurrence, 0, len(results))
+	for _, r := range results {
+		occurrences = append(occurrences, r.Occurrence)
+	}
+	c.printOccurrences(occurrences)
+	c.outputLn(fmt.Sprintf("Найдено событий: %d", len(results)))
+	logger.Info(fmt.Sprintf("Поиск: найдено %d событий", len(results)))
 }
 
 func (c *Cmd) handleRemove(parts []string) {
 	logger.Info("Обработка команды remove")
 	if len(parts) < 2 {
-		c.outputLn("Формат: " + removeFormat)
+		c.usage(removeFormat)
 		logger.Error("Неверный формат команды remove")
 		return
 	}
@@ -78,18 +337,18 @@ func (c *Cmd) handleRemove(parts []string) {
 	ID := parts[1]
 	deletedEvent, err := c.calendar.DeleteEvent(ID)
 	if err != nil {
-		c.outputLn("Ошибка: " + err.Error())
+		c.fail("Ошибка", err)
 		logger.Error("Ошибка удаления события: " + err.Error())
 		return
 	}
-	c.outputLn("Событие удалено: \"" + deletedEvent.Title + "\"")
-	logger.Info(fmt.Sprintf("Событие удалено: ID=%s, Title=%s", deletedEvent.ID, deletedEvent.Title))
+	c.outputLn("Событие перемещено в корзину: \"" + deletedEvent.Title + "\"")
+	logger.Info(fmt.Sprintf("Событие перемещено в корзину: ID=%s, Title=%s", deletedEvent.ID, deletedEvent.Title))
 }
 
 func (c *Cmd) handleUpdate(parts []string) {
 	logger.Info("Обработка команды update")
 	if len(parts) < 5 {
-		c.outputLn("Формат: " + updateFormat)
+		c.usage(updateFormat)
 		logger.Error("Неверный формат команды update")
 		return
 	}
@@ -97,22 +356,28 @@ func (c *Cmd) handleUpdate(parts []string) {
 	ID := parts[1]
 	newTitle := parts[2]
 	newDate := parts[3]
+	newEnd := "_"
 	newPriority := events.Priority(parts[4])
+	if len(parts) > 5 {
+		newEnd = parts[4]
+		newPriority = events.Priority(parts[5])
+	}
 
-	oldTitle, newTitle, err := c.calendar.EditEvent(ID, newTitle, newDate, newPriority)
+	oldTitle, newTitle, err := c.calendar.EditEvent(ID, newTitle, newDate, newEnd, newPriority)
 	if err != nil {
-		c.outputLn("Ошибка: " + err.Error())
+		c.fail("Ошибка", err)
 		logger.Error("Ошибка обновления события: " + err.Error())
 		return
 	}
 	c.outputLn(fmt.Sprintf("Событие обновлено: \"%s\" на \"%s\"", oldTitle, newTitle))
 	logger.Info(fmt.Sprintf("Событие обновлено: ID=%s, OldTitle=%s, NewTitle=%s", ID, oldTitle, newTitle))
+	c.warnConflicts(ID)
 }
 
 func (c *Cmd) handleRemind(parts []string) {
 	logger.Info("Обработка команды remind")
 	if len(parts) < 4 {
-		c.outputLn("Формат: " + remindFormat)
+		c.usage(remindFormat)
 		logger.Error("Неверный формат команды remind")
 		return
 	}
@@ -121,55 +386,300 @@ func (c *Cmd) handleRemind(parts []string) {
 	message := strings.TrimSpace(parts[2])
 	at := parts[3]
 
-	if err := c.calendar.SetEventReminder(id, message, at); err != nil {
-		c.outputLn("Ошибка: " + err.Error())
+	r, err := c.calendar.SetEventReminder(id, message, at)
+	if err != nil {
+		c.fail("Ошибка", err)
 		logger.Error("Ошибка добавления напоминания: " + err.Error())
 		return
 	}
-	c.outputLn("Добавлено напоминание: \"" + message + "\"")
-	logger.Info(fmt.Sprintf("Добавлено напоминание: ID=%s, Message=%s", id, message))
+	c.outputLn(fmt.Sprintf("Добавлено напоминание #%s: \"%s\" - \"%s\"", r.ID, r.Message, datetime.FormatLocal(r.At)))
+	logger.Info(fmt.Sprintf("Добавлено напоминание: ID=%s, ReminderID=%s, Message=%s", id, r.ID, message))
+}
+
+func (c *Cmd) handleReminders(parts []string) {
+	logger.Info("Обработка команды reminders")
+	if len(parts) < 2 {
+		c.usage(remindersFormat)
+		logger.Error("Неверный формат команды reminders")
+		return
+	}
+
+	id := parts[1]
+	reminders, err := c.calendar.GetEventReminders(id)
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка получения напоминаний: " + err.Error())
+		return
+	}
+	if len(reminders) == 0 {
+		c.outputLn("У события нет напоминаний")
+		logger.Info(fmt.Sprintf("У события нет напоминаний: ID=%s", id))
+		return
+	}
+
+	for _, r := range reminders {
+		status := "ожидает"
+		if r.Sent {
+			status = "отправлено"
+		}
+		when := datetime.FormatLocal(r.At)
+		if r.Offset > 0 {
+			when += fmt.Sprintf(" (за %s до начала)", r.Offset)
+		}
+		c.outputLn(fmt.Sprintf("#%s: \"%s\" - %s, %s", r.ID, r.Message, when, status))
+	}
+	logger.Info(fmt.Sprintf("Выведено %d напоминаний: ID=%s", len(reminders), id))
 }
 
 func (c *Cmd) handleRemindCancel(parts []string) {
 	logger.Info("Обработка команды remind-cancel")
 	if len(parts) < 2 {
-		c.outputLn("Формат: " + cancelRemindFormat)
+		c.usage(cancelRemindFormat)
 		logger.Error("Неверный формат команды remind-cancel")
 		return
 	}
 
 	id := parts[1]
-	if err := c.calendar.CancelEventReminder(id); err != nil {
-		c.outputLn("Ошибка: " + err.Error())
+	reminderID := ""
+	if len(parts) > 2 {
+		reminderID = strings.TrimPrefix(parts[2], "#")
+	}
+	if err := c.calendar.CancelEventReminder(id, reminderID); err != nil {
+		c.fail("Ошибка", err)
 		logger.Error("Ошибка отмены напоминания: " + err.Error())
 		return
 	}
-	c.outputLn("Напоминание отменено")
-	logger.Info(fmt.Sprintf("Напоминание отменено: ID=%s", id))
+	if reminderID == "" {
+		c.outputLn("Напоминания отменены")
+	} else {
+		c.outputLn("Напоминание #" + reminderID + " отменено")
+	}
+	logger.Info(fmt.Sprintf("Напоминание отменено: ID=%s, ReminderID=%s", id, reminderID))
+}
+
+func (c *Cmd) handleRepeat(parts []string) {
+	logger.Info("Обработка команды repeat")
+	if len(parts) < 3 {
+		c.usage(repeatFormat)
+		logger.Error("Неверный формат команды repeat")
+		return
+	}
+
+	id := parts[1]
+	rule := parts[2]
+	if err := c.calendar.SetEventRecurrence(id, rule); err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка установки повторения: " + err.Error())
+		return
+	}
+	c.outputLn("Повторение установлено")
+	logger.Info(fmt.Sprintf("Повторение установлено: ID=%s, Rule=%s", id, rule))
+}
+
+func (c *Cmd) handleRepeatCancel(parts []string) {
+	logger.Info("Обработка команды repeat-cancel")
+	if len(parts) < 2 {
+		c.usage(cancelRepeatFormat)
+		logger.Error("Неверный формат команды repeat-cancel")
+		return
+	}
+
+	id := parts[1]
+	if err := c.calendar.CancelEventRecurrence(id); err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка отмены повторения: " + err.Error())
+		return
+	}
+	c.outputLn("Повторение отменено")
+	logger.Info(fmt.Sprintf("Повторение отменено: ID=%s", id))
+}
+
+func (c *Cmd) handleRepeatSkip(parts []string) {
+	logger.Info("Обработка команды repeat-skip")
+	if len(parts) < 3 {
+		c.usage(skipRepeatFormat)
+		logger.Error("Неверный формат команды repeat-skip")
+		return
+	}
+
+	id := parts[1]
+	at := parts[2]
+	if err := c.calendar.SkipOccurrence(id, at); err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка пропуска вхождения: " + err.Error())
+		return
+	}
+	c.outputLn("Вхождение пропущено: " + at)
+	logger.Info(fmt.Sprintf("Вхождение пропущено: ID=%s, At=%s", id, at))
+}
+
+func (c *Cmd) handleRepeatEdit(parts []string) {
+	logger.Info("Обработка команды repeat-edit")
+	if len(parts) < 5 {
+		c.usage(editRepeatFormat)
+		logger.Error("Неверный формат команды repeat-edit")
+		return
+	}
+
+	id := parts[1]
+	at := parts[2]
+	if err := c.calendar.EditOccurrence(id, at, parts[3], parts[4]); err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка изменения вхождения: " + err.Error())
+		return
+	}
+	c.outputLn("Вхождение изменено: " + at)
+	logger.Info(fmt.Sprintf("Вхождение изменено: ID=%s, At=%s", id, at))
+}
+
+func (c *Cmd) handleExportICS(parts []string) {
+	logger.Info("Обработка команды export-ics")
+	if len(parts) < 2 {
+		c.usage(exportICSFormat)
+		logger.Error("Неверный формат команды export-ics")
+		return
+	}
+
+	filename := parts[1]
+	eventsList := c.calendar.GetEvents()
+	if err := exportICS(filename, eventsList); err != nil {
+		c.fail("Ошибка экспорта", err)
+		logger.Error("Ошибка экспорта в iCalendar: " + err.Error())
+		return
+	}
+	c.outputLn(fmt.Sprintf("Экспортировано событий: %d в %s", len(eventsList), filename))
+	logger.Info(fmt.Sprintf("Экспортировано %d событий в %s", len(eventsList), filename))
+}
+
+func exportICS(filename string, eventsList []*events.Event) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := ical.Export(f, eventsList); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (c *Cmd) handleImportICS(parts []string) {
+	logger.Info("Обработка команды import-ics")
+	if len(parts) < 2 {
+		c.usage(importICSFormat)
+		logger.Error("Неверный формат команды import-ics")
+		return
+	}
+
+	filename := parts[1]
+	f, err := os.Open(filename)
+	if err != nil {
+		c.fail("Ошибка импорта", err)
+		logger.Error("Ошибка открытия файла iCalendar: " + err.Error())
+		return
+	}
+	defer f.Close()
+
+	result, err := ical.Import(f)
+	if err != nil {
+		c.fail("Ошибка импорта", err)
+		logger.Error("Ошибка разбора iCalendar: " + err.Error())
+		return
+	}
+
+	for _, w := range result.Warnings {
+		c.outputLn("Предупреждение: " + w)
+		logger.Error("Предупреждение импорта iCalendar: " + w)
+	}
+	added, updated := c.calendar.ImportEvents(result.Events)
+	c.outputLn(fmt.Sprintf("Импорт завершён: добавлено %d, обновлено %d, предупреждений %d", added, updated, len(result.Warnings)))
+	logger.Info(fmt.Sprintf("Импорт из %s: добавлено %d, обновлено %d", filename, added, updated))
+}
+
+func (c *Cmd) helpRow(name string, format string) {
+	c.outputLn(fmt.Sprintf(": %*s: %-*s:", helpNameWidth, name, helpFormatWidth, format))
+}
+
+func (c *Cmd) helpNote(text string) {
+	c.outputLn(fmt.Sprintf(": %-*s:", helpNameWidth+helpFormatWidth+2, text))
+}
+
+func (c *Cmd) helpSeparator() {
+	c.outputLn(":" + strings.Repeat(".", helpNameWidth+helpFormatWidth+3) + ":")
 }
 
 func (c *Cmd) handleHelp() {
 	logger.Info("Обработка команды help")
-	c.outputLn(".......................................................................................")
-	c.outputLn(fmt.Sprintf(":             Добавить: %s         :", addFormat))
-	c.outputLn(fmt.Sprintf(":              Удалить: %s                                                   :", removeFormat))
-	c.outputLn(fmt.Sprintf(":             Обновить: %s :", updateFormat))
-	c.outputLn(fmt.Sprintf(":          Напоминание: %s           :", remindFormat))
-	c.outputLn(fmt.Sprintf(": Отменить напоминание: %s                                            :", cancelRemindFormat))
-	c.outputLn(":               Список: list                                                          :")
-	c.outputLn(":                  Лог: log                                                           :")
-	c.outputLn(":        Сохранить лог: log-save                                                      :")
-	c.outputLn(":        Загрузить лог: log-load                                                      :")
-	c.outputLn(":                Выход: exit                                                          :")
-	c.outputLn(":.....................................................................................:")
-	c.outputLn(fmt.Sprintf(": Пример шаблона даты и времени: %s                                     :", datetime.LayoutFormat))
-	c.outputLn(": Пример шаблона duration для напоминания: 1h50m30s                                   :")
-	c.outputLn(fmt.Sprintf(": Допустимые приоритеты: %s, %s, %s                                            :", events.PriorityLow, events.PriorityMedium, events.PriorityHigh))
-	c.outputLn(fmt.Sprintf(": Данные сохраняются в файл %s при выходе из программы                :", config.DataFileName))
-	c.outputLn(fmt.Sprintf(": Логи команд сохраняются в файл %s и архивируются в %s    :", config.ZipLogEntryName, config.LogArchiveName))
-	c.outputLn(fmt.Sprintf(": Логи приложения хранятся в файле %s                                       :", config.LogFileName))
-	c.outputLn(": При обновлении события некоторые поля можно пропустить вводом символа <_>           :")
-	c.outputLn(":.....................................................................................:")
+	c.helpSeparator()
+	c.helpRow("Добавить", addFormat)
+	c.helpRow("Удалить", removeFormat)
+	c.helpRow("Обновить", updateFormat)
+	c.helpRow("Напоминание", remindFormat)
+	c.helpRow("Отменить напоминание", cancelRemindFormat)
+	c.helpRow("Напоминания события", remindersFormat)
+	c.helpRow("Повторять", repeatFormat)
+	c.helpRow("Отменить повторение", cancelRepeatFormat)
+	c.helpRow("Пропустить вхождение", skipRepeatFormat)
+	c.helpRow("Изменить вхождение", editRepeatFormat)
+	c.helpRow("Список", listFormat)
+	c.helpRow("Поиск", searchFormat)
+	c.helpRow("Пересечения", conflictsFormat)
+	c.helpRow("Свободное время", "free <duration> [параметры]")
+	c.helpRow("Сегодня", todayFormat)
+	c.helpRow("Неделя", weekFormat)
+	c.helpRow("Месяц", monthFormat)
+	c.helpRow("Экспорт в iCalendar", exportICSFormat)
+	c.helpRow("Импорт из iCalendar", importICSFormat)
+	c.helpRow("Выполнить скрипт", sourceFormat)
+	c.helpRow("Корзина", "trash")
+	c.helpRow("Восстановить", restoreFormat)
+	c.helpRow("Очистить корзину", trashEmptyFormat)
+	c.helpRow("Отменить", "undo")
+	c.helpRow("Повторить", "redo")
+	c.helpRow("История", historyFormat)
+	c.helpRow("Календари", "calendars")
+	c.helpRow("Создать календарь", calendarCreateFormat)
+	c.helpRow("Текущий календарь", calendarUseFormat)
+	c.helpRow("Перенести событие", moveFormat)
+	c.helpRow("Сменить пароль", "passwd")
+	c.helpRow("Настройки", configFormat)
+	c.helpRow("Лог", "log")
+	c.helpRow("Сохранить лог", "log-save")
+	c.helpRow("Загрузить лог", "log-load")
+	c.helpRow("Выход", "exit (или Ctrl+D)")
+	c.helpSeparator()
+	c.helpNote(fmt.Sprintf("Пример шаблона даты и времени: %s", datetime.LayoutFormat))
+	c.helpNote(fmt.Sprintf("Событие на весь день задаётся датой без времени: %s", datetime.DateLayoutFormat))
+	c.helpNote("Окончание события: дата и время, дата или duration (2h, 1d)")
+	c.helpNote("Пример шаблона duration для напоминания: 1h50m30s")
+	c.helpNote("Напоминание относительно начала события: -15m, -1d")
+	c.helpNote("Фильтры list: --from <дата>, --to <дата>, --priority <приоритет>, --has-reminder, --past, --upcoming")
+	c.helpNote(fmt.Sprintf("Размер страницы list по умолчанию: %d", config.ListPageSize))
+	c.helpNote(fmt.Sprintf("Неделя начинается с понедельника; %s — событие с высоким приоритетом", markerPriority))
+	c.helpNote("Без ID напоминания remind-cancel отменяет все напоминания события")
+	c.helpNote(fmt.Sprintf("Для пересечений событие без окончания длится %s", config.DefaultEventDuration))
+	c.helpNote("Параметры free: --from <дата>, --to <дата>, --work-hours 10-18, --skip-weekends")
+	c.helpNote(fmt.Sprintf("free --book \"название\" [--priority <приоритет>] добавляет событие в первый слот; рабочие часы по умолчанию %s-%s", clockString(config.WorkDayStart), clockString(config.WorkDayEnd)))
+	c.helpNote("Вместо полного ID можно указать его однозначное начало, например первые 8 символов")
+	c.helpNote(fmt.Sprintf("Допустимые приоритеты: %s, %s, %s", events.PriorityLow, events.PriorityMedium, events.PriorityHigh))
+	c.helpNote(fmt.Sprintf("Данные сохраняются в файл %s после каждого изменения", config.DataFileName))
+	c.helpNote(fmt.Sprintf("С флагом --storage %s данные хранятся в базе %s и сохраняются по событию", config.StorageSQLite, config.SqliteFileName))
+	c.helpNote("remove перемещает событие в корзину; trash-empty удаляет его окончательно")
+	c.helpNote(fmt.Sprintf("Новые события добавляются в текущий календарь (по умолчанию %s); приоритет можно опустить, если он задан у календаря", events.DefaultCalendar))
+	c.helpNote(fmt.Sprintf("Цвета календаря: %s или #RRGGBB; список календарей хранится вместе с событиями", strings.Join(events.Colors, ", ")))
+	c.helpNote(fmt.Sprintf("Изменения записываются в журнал %s; history без ID показывает последние %d записей", config.JournalFileName, config.HistoryLimit))
+	c.helpNote(fmt.Sprintf("С флагом --encrypt данные шифруются паролем; пароль можно передать в переменной %s", config.PassphraseEnv))
+	c.helpNote(fmt.Sprintf("Логи команд сохраняются в файл %s и архивируются в %s", config.ZipLogEntryName, config.LogArchiveName))
+	c.helpNote(fmt.Sprintf("Логи приложения хранятся в файле %s", config.LogFileName))
+	c.helpNote(fmt.Sprintf("Настройки читаются из %s; их переопределяют переменные окружения и флаги: list.page_size → CALENDAR_LIST_PAGE_SIZE, --list-page-size", config.FilePath))
+	c.helpNote(fmt.Sprintf("Профиль: %s; --profile <имя> или %s открывает отдельные календарь, логи и настройки", config.Profile, config.ProfileEnv))
+	c.helpNote("Команды можно выполнять без оболочки: calendar add ..., calendar list --json, calendar remove <ID>")
+	c.helpNote("Скрипт: calendar --script <файл|-> [--continue] или команды через stdin; # — комментарий")
+	c.helpNote("При обновлении события некоторые поля можно пропустить вводом символа <_>")
+	c.helpNote("Окончание события можно убрать вводом символа <->")
+	c.helpNote("Правило повторения: FREQ=DAILY|WEEKLY|MONTHLY|YEARLY;INTERVAL=n;BYDAY=MO,TU;COUNT=n;UNTIL=20251231")
+	c.helpNote(fmt.Sprintf("Повторяющиеся события показываются в списке на %d дней вперёд", int(config.RecurrenceListHorizon/datetime.Day)))
+	c.helpSeparator()
 }
 
 func (c *Cmd) handleLog() {
@@ -189,7 +699,7 @@ func (c *Cmd) handleLog() {
 func (c *Cmd) handleLogSave() {
 	logger.Info("Обработка команды log-save")
 	if err := c.logHandler.Save(); err != nil {
-		c.outputLn("Ошибка сохранения лога: " + err.Error())
+		c.fail("Ошибка сохранения лога", err)
 		logger.Error("Ошибка сохранения лога: " + err.Error())
 		return
 	}
@@ -200,7 +710,7 @@ func (c *Cmd) handleLogSave() {
 func (c *Cmd) handleLogLoad() {
 	logger.Info("Обработка команды log-load")
 	if err := c.logHandler.Load(); err != nil {
-		c.outputLn("Ошибка загрузки лога: " + err.Error())
+		c.fail("Ошибка загрузки лога", err)
 		logger.Error("Ошибка загрузки лога: " + err.Error())
 		return
 	}
@@ -210,20 +720,12 @@ func (c *Cmd) handleLogLoad() {
 
 func (c *Cmd) handleExit() {
 	logger.Info("Обработка команды exit")
-	for _, e := range c.calendar.GetEvents() {
-		if e.Reminder != nil {
-			e.Reminder.Stop()
-		}
-	}
 
 	err := c.calendar.Save()
 	if err != nil {
-		c.outputLn("Ошибка сохранения данных: " + err.Error())
+		c.fail("Ошибка сохранения данных", err)
 		logger.Error("Ошибка сохранения данных: " + err.Error())
 		return
 	}
-	c.calendar.Close()
-	c.wg.Wait()
-	logger.Info("Приложение завершило работу")
-	os.Exit(0)
+	c.exiting = true
 }
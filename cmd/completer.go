@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+// idCommands — команды, первым аргументом которых является ID события.
+var idCommands = map[string]bool{
+	"remove":        true,
+	"update":        true,
+	"remind":        true,
+	"remind-cancel": true,
+	"reminders":     true,
+	"repeat":        true,
+	"repeat-cancel": true,
+	"repeat-skip":   true,
+	"repeat-edit":   true,
+	"history":       true,
+	"move":          true,
+}
+
+var prioritySuggestions = []prompt.Suggest{
+	{Text: string(events.PriorityLow), Description: "Низкий приоритет"},
+	{Text: string(events.PriorityMedium), Description: "Средний приоритет"},
+	{Text: string(events.PriorityHigh), Description: "Высокий приоритет"},
+}
+
+// splitInput делит ввод до курсора на завершённые аргументы и аргумент под курсором,
+// учитывая двойные кавычки.
+func splitInput(text string) ([]string, string) {
+	var args []string
+	var cur strings.Builder
+	inQuote, hasArg := false, false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasArg = true
+		case r == ' ' && !inQuote:
+			if hasArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasArg = true
+		}
+	}
+	return args, cur.String()
+}
+
+func (c *Cmd) argumentSuggestions(args []string, word string) []prompt.Suggest {
+	cmd := strings.ToLower(args[0])
+	pos := len(args)
+	prev := args[len(args)-1]
+
+	switch {
+	case pos == 1 && idCommands[cmd]:
+		return c.idSuggestions(word)
+	case pos == 1 && cmd == "restore":
+		return candidateSuggestions(c.calendar.TrashIDs(word))
+	case pos == 1 && cmd == "calendar-use", pos == 2 && cmd == "move", prev == "--calendar":
+		return c.calendarSuggestions(word)
+	case cmd == "calendar-create" && prev == "--color":
+		return colorSuggestions(word)
+	case pos == 1 && cmd == "config":
+		return configSuggestions(word)
+	case cmd == "add" && pos >= 3, cmd == "update" && pos >= 4:
+		return prompt.FilterHasPrefix(prioritySuggestions, word, true)
+	case (cmd == "list" || cmd == "free" || cmd == "calendar-create") && prev == "--priority":
+		return prompt.FilterHasPrefix(prioritySuggestions, word, true)
+	case cmd == "search" && strings.HasPrefix(word, "priority:"):
+		var list []prompt.Suggest
+		for _, s := range prioritySuggestions {
+			list = append(list, prompt.Suggest{Text: "priority:" + s.Text, Description: s.Description})
+		}
+		return prompt.FilterHasPrefix(list, word, true)
+	}
+	return []prompt.Suggest{}
+}
+
+func (c *Cmd) idSuggestions(prefix string) []prompt.Suggest {
+	return candidateSuggestions(c.calendar.IDs(prefix))
+}
+
+func candidateSuggestions(candidates []calendar.Candidate) []prompt.Suggest {
+	list := make([]prompt.Suggest, 0, len(candidates))
+	for _, e := range candidates {
+		list = append(list, prompt.Suggest{Text: e.ID, Description: e.Title})
+	}
+	return list
+}
+
+func configSuggestions(prefix string) []prompt.Suggest {
+	var list []prompt.Suggest
+	for _, s := range config.Settings() {
+		list = append(list, prompt.Suggest{Text: s.Key, Description: s.Usage})
+	}
+	return prompt.FilterHasPrefix(list, prefix, true)
+}
+
+func (c *Cmd) calendarSuggestions(prefix string) []prompt.Suggest {
+	var list []prompt.Suggest
+	for _, name := range c.calendar.CalendarNames(prefix) {
+		list = append(list, prompt.Suggest{Text: name})
+	}
+	return list
+}
+
+func colorSuggestions(prefix string) []prompt.Suggest {
+	var list []prompt.Suggest
+	for _, color := range events.Colors {
+		list = append(list, prompt.Suggest{Text: color})
+	}
+	return prompt.FilterHasPrefix(list, prefix, true)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const configFormat = "config [ключ]"
+
+// handleConfig выводит действующие настройки и источник каждого значения.
+func (c *Cmd) handleConfig(parts []string) {
+	logger.Info("Обработка команды config")
+	list := config.Settings()
+	switch len(parts) {
+	case 1:
+	case 2:
+		s, ok := config.Lookup(parts[1])
+		if !ok {
+			c.fail("Ошибка", fmt.Errorf("%w %q", config.ErrUnknownSetting, parts[1]))
+			logger.Error("Неизвестная настройка: " + parts[1])
+			return
+		}
+		list = []*config.Setting{s}
+	default:
+		c.usage(configFormat)
+		logger.Error("Неверный формат команды config")
+		return
+	}
+
+	if len(parts) == 1 {
+		state := "не найден"
+		if config.FileFound {
+			state = "загружен"
+		}
+		c.outputLn("Профиль: " + config.Profile)
+		c.outputLn(fmt.Sprintf("Файл настроек: %s (%s)", config.FilePath, state))
+	}
+
+	keyWidth, valueWidth := 0, 0
+	for _, s := range list {
+		keyWidth = max(keyWidth, len(s.Key))
+		valueWidth = max(valueWidth, len([]rune(settingValue(s))))
+	}
+	for _, s := range list {
+		source := s.Source.String()
+		if s.Origin != "" {
+			source += " " + s.Origin
+		}
+		c.outputLn(fmt.Sprintf("%-*s  %-*s  %s", keyWidth, s.Key, valueWidth, settingValue(s), source))
+	}
+}
+
+// settingValue берёт в кавычки пустые значения и значения с пробелами по краям,
+// чтобы их было видно в выводе.
+func settingValue(s *config.Setting) string {
+	v := s.Value()
+	if v == "" || strings.TrimSpace(v) != v {
+		return strconv.Quote(v)
+	}
+	return v
+}
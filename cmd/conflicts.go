@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const conflictsFormat = "conflicts [дата|ГГГГ-ММ|<с> <по>]"
+
+func (c *Cmd) warnConflicts(id string) {
+	list, err := c.calendar.EventConflicts(id)
+	if err != nil || len(list) == 0 {
+		return
+	}
+	c.outputLn(fmt.Sprintf("Внимание: событие пересекается с другими событиями (%d):", len(list)))
+	for _, o := range list {
+		c.outputLn("  " + conflictLine(o))
+	}
+	logger.Info(fmt.Sprintf("Событие ID=%s пересекается с %d событиями", id, len(list)))
+}
+
+func conflictLine(o events.Occurrence) string {
+	marker := ""
+	if o.Event.Priority == events.PriorityHigh {
+		marker = markerPriority + " "
+	}
+	return fmt.Sprintf("%s%s \"%s\" [%s]", marker, datetime.FormatRange(o.StartAt, o.EndAt, o.Event.AllDay), o.Title, shortID(o.Event.ID))
+}
+
+// parseRange разбирает период команды: без аргументов — config.RecurrenceListHorizon
+// от текущего момента, дата — день, ГГГГ-ММ — месяц, две границы — интервал.
+func parseRange(args []string) (time.Time, time.Time, error) {
+	switch len(args) {
+	case 0:
+		now := time.Now()
+		return now, now.Add(config.RecurrenceListHorizon), nil
+	case 1:
+		if t, err := datetime.ParseLocalDate(args[0]); err == nil {
+			return t, t.AddDate(0, 0, 1), nil
+		}
+		if t, err := datetime.ParseLocalMonth(args[0]); err == nil {
+			return t, t.AddDate(0, 1, 0), nil
+		}
+		return time.Time{}, time.Time{}, events.ErrInvalidDate
+	default:
+		from, err := parseBound(args[0], false)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to, err := parseBound(args[1], true)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if !to.After(from) {
+			return time.Time{}, time.Time{}, events.ErrInvalidEnd
+		}
+		return from, to, nil
+	}
+}
+
+func (c *Cmd) handleConflicts(parts []string) {
+	logger.Info("Обработка команды conflicts")
+	if len(parts) > 3 {
+		c.usage(conflictsFormat)
+		logger.Error("Неверный формат команды conflicts")
+		return
+	}
+	from, to, err := parseRange(parts[1:])
+	if err != nil {
+		c.fail("Ошибка", fmt.Errorf("ошибка проверки периода: %w", err))
+		return
+	}
+
+	conflicts := c.calendar.FindConflicts(from, to)
+	if len(conflicts) == 0 {
+		c.outputLn("Пересечений нет")
+		logger.Info("Пересечений нет")
+		return
+	}
+	for _, cf := range conflicts {
+		c.outputLn(conflictLine(cf.First) + "  ↔  " + conflictLine(cf.Second))
+	}
+	c.outputLn(fmt.Sprintf("Найдено пересечений: %d", len(conflicts)))
+	logger.Info(fmt.Sprintf("Найдено %d пересечений с %s по %s", len(conflicts), datetime.FormatLocal(from), datetime.FormatLocal(to)))
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const freeFormat = "free <duration> [--from <дата>] [--to <дата>] [--work-hours 10-18] [--skip-weekends] [--book \"название\" [--priority <приоритет>]]"
+
+type freeOptions struct {
+	query    calendar.SlotQuery
+	book     string
+	priority events.Priority
+}
+
+func parseFreeArgs(args []string) (freeOptions, error) {
+	now := time.Now()
+	opts := freeOptions{
+		query: calendar.SlotQuery{
+			From:      now,
+			WorkStart: config.WorkDayStart,
+			WorkEnd:   config.WorkDayEnd,
+			Step:      config.FreeSlotStep,
+			Limit:     config.FreeSlotsLimit,
+		},
+		priority: events.PriorityMedium,
+	}
+	q := &opts.query
+
+	d, err := datetime.ParseDuration(args[0])
+	if err != nil {
+		return opts, fmt.Errorf("%s: неверная длительность", args[0])
+	}
+	q.Duration = d
+
+	for i := 1; i < len(args); i++ {
+		flag := args[i]
+		if flag == "--skip-weekends" {
+			q.SkipWeekends = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s: нет значения", flag)
+		}
+		i++
+		v := args[i]
+
+		switch flag {
+		case "--from", "--to":
+			t, err := parseBound(v, flag == "--to")
+			if err != nil {
+				return opts, fmt.Errorf("%s: %w", flag, err)
+			}
+			if flag == "--from" {
+				q.From = t
+			} else {
+				q.To = t
+			}
+		case "--work-hours":
+			if q.WorkStart, q.WorkEnd, err = parseWorkHours(v); err != nil {
+				return opts, fmt.Errorf("%s: %w", flag, err)
+			}
+		case "--book":
+			opts.book = v
+		case "--priority":
+			opts.priority = events.Priority(v)
+			if err := opts.priority.Validate(); err != nil {
+				return opts, err
+			}
+		default:
+			return opts, fmt.Errorf("%s: неизвестный параметр", flag)
+		}
+	}
+
+	if q.To.IsZero() {
+		q.To = datetime.StartOfDay(q.From.Add(config.FreeSearchHorizon)).AddDate(0, 0, 1)
+	}
+	if !q.To.After(q.From) {
+		return opts, events.ErrInvalidEnd
+	}
+	return opts, nil
+}
+
+// parseWorkHours разбирает интервал рабочих часов "10-18" или "9:30-18:00";
+// "0-24" — весь день.
+func parseWorkHours(s string) (time.Duration, time.Duration, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, calendar.ErrInvalidWorkHours
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start >= end {
+		return 0, 0, calendar.ErrInvalidWorkHours
+	}
+	if start == 0 && end == datetime.Day {
+		return 0, 0, nil
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	hh, mm, hasMinutes := strings.Cut(strings.TrimSpace(s), ":")
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 24 {
+		return 0, calendar.ErrInvalidWorkHours
+	}
+	m := 0
+	if hasMinutes {
+		if m, err = strconv.Atoi(mm); err != nil || m < 0 || m > 59 || (h == 24 && m > 0) {
+			return 0, calendar.ErrInvalidWorkHours
+		}
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func (c *Cmd) handleFree(parts []string) {
+	logger.Info("Обработка команды free")
+	if len(parts) < 2 {
+		c.usage(freeFormat)
+		logger.Error("Неверный формат команды free")
+		return
+	}
+	opts, err := parseFreeArgs(parts[1:])
+	if err != nil {
+		if isAny(err, invalidArgumentErrors) {
+			c.fail("Ошибка", err)
+		} else {
+			c.fail("Ошибка", &usageError{format: freeFormat, cause: err})
+			if !c.batch {
+				c.outputLn("Формат: " + freeFormat)
+			}
+		}
+		logger.Error("Неверный формат команды free: " + err.Error())
+		return
+	}
+
+	slots, err := c.calendar.FreeSlots(opts.query)
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка поиска свободного времени: " + err.Error())
+		return
+	}
+	if len(slots) == 0 {
+		c.fail("Ошибка", calendar.ErrNoFreeSlot)
+		logger.Info("Свободного времени не найдено")
+		return
+	}
+
+	if opts.book != "" {
+		c.bookSlot(opts, slots[0])
+		return
+	}
+	for _, s := range slots {
+		c.outputLn(fmt.Sprintf("%s %s-%s (%s)", datetime.FormatDayHeader(s.Start),
+			s.Start.Format(datetime.TimeLayoutFormat), slotEnd(s), s.Duration()))
+	}
+	logger.Info(fmt.Sprintf("Найдено свободных интервалов: %d", len(slots)))
+}
+
+func (c *Cmd) bookSlot(opts freeOptions, slot calendar.Slot) {
+	start := slot.Start
+	end := start.Add(opts.query.Duration)
+	e, err := c.calendar.AddEvent(opts.book, datetime.FormatLocal(start), datetime.FormatLocal(end), opts.priority)
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка добавления события: " + err.Error())
+		return
+	}
+	c.outputLn(fmt.Sprintf("Событие: \"%s\" добавлено на %s, ID: %s", e.Title, datetime.FormatRange(start, end, false), e.ID))
+	logger.Info(fmt.Sprintf("Событие добавлено в свободный слот: ID=%s, Title=%s", e.ID, e.Title))
+}
+
+func clockString(d time.Duration) string {
+	return fmt.Sprintf("%d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// slotEnd выводит окончание слота, совпадающее с полуночью, как 24:00.
+func slotEnd(s calendar.Slot) string {
+	if s.End.Equal(datetime.StartOfDay(s.End)) {
+		return "24:00"
+	}
+	return s.End.Format(datetime.TimeLayoutFormat)
+}
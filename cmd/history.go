@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const historyFormat = "history [ID]"
+
+var opNames = map[calendar.Op]string{
+	calendar.OpAdd:          "добавление",
+	calendar.OpUpdate:       "изменение",
+	calendar.OpDelete:       "удаление",
+	calendar.OpRemind:       "напоминание",
+	calendar.OpRemindCancel: "отмена напоминания",
+	calendar.OpRepeat:       "повторение",
+	calendar.OpRepeatCancel: "отмена повторения",
+	calendar.OpRepeatSkip:   "пропуск вхождения",
+	calendar.OpRepeatEdit:   "изменение вхождения",
+	calendar.OpImport:       "импорт",
+	calendar.OpRestore:      "восстановление",
+	calendar.OpPurge:        "очистка корзины",
+	calendar.OpMove:         "перенос",
+	calendar.OpUndo:         "отмена",
+	calendar.OpRedo:         "повтор",
+}
+
+// eventFields — порядок полей события в истории; остальные поля выводятся после них.
+var eventFields = []string{"title", "start_at", "end_at", "all_day", "priority", "calendar", "recurrence", "exceptions", "reminders"}
+
+func (c *Cmd) handleUndo() {
+	logger.Info("Обработка команды undo")
+	entry, err := c.calendar.Undo()
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка отмены: " + err.Error())
+		return
+	}
+	c.outputLn("Отменено: " + entrySummary(entry))
+	logger.Info(fmt.Sprintf("Отменена запись журнала #%d", entry.Seq))
+}
+
+func (c *Cmd) handleRedo() {
+	logger.Info("Обработка команды redo")
+	entry, err := c.calendar.Redo()
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка повтора: " + err.Error())
+		return
+	}
+	c.outputLn("Повторено: " + entrySummary(entry))
+	logger.Info(fmt.Sprintf("Повторена запись журнала #%d", entry.Seq))
+}
+
+func (c *Cmd) handleHistory(parts []string) {
+	logger.Info("Обработка команды history")
+	if len(parts) > 2 {
+		c.usage(historyFormat)
+		logger.Error("Неверный формат команды history")
+		return
+	}
+	id := ""
+	if len(parts) == 2 {
+		id = parts[1]
+	}
+
+	list, err := c.calendar.History(id)
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка получения истории: " + err.Error())
+		return
+	}
+	if len(list) == 0 {
+		c.outputLn("История пуста")
+		logger.Info("История пуста")
+		return
+	}
+
+	if id == "" {
+		if len(list) > config.HistoryLimit {
+			list = list[len(list)-config.HistoryLimit:]
+		}
+		for _, e := range list {
+			c.outputLn(fmt.Sprintf("#%d %s %s", e.Seq, datetime.FormatLocal(e.At), entrySummary(e)))
+		}
+		logger.Info(fmt.Sprintf("Выведено %d записей истории", len(list)))
+		return
+	}
+
+	for _, e := range list {
+		c.outputLn(entryHeader(e))
+		for _, line := range changeLines(e.Changes[0]) {
+			c.outputLn("  " + line)
+		}
+	}
+	logger.Info(fmt.Sprintf("Выведена история события %s: %d записей", id, len(list)))
+}
+
+func entryHeader(e calendar.JournalEntry) string {
+	header := fmt.Sprintf("#%d %s %s", e.Seq, datetime.FormatLocal(e.At), opNames[e.Op])
+	if e.Ref != 0 {
+		header += fmt.Sprintf(" #%d", e.Ref)
+	}
+	return header
+}
+
+func entrySummary(e calendar.JournalEntry) string {
+	summary := opNames[e.Op]
+	if e.Ref != 0 {
+		summary += fmt.Sprintf(" #%d", e.Ref)
+	}
+	for i, ch := range e.Changes {
+		if i == config.HistorySummaryEvents {
+			summary += fmt.Sprintf(" и ещё %d", len(e.Changes)-i)
+			break
+		}
+		summary += fmt.Sprintf(" \"%s\" [%s]", ch.Title(), shortID(ch.EventID))
+	}
+	return summary
+}
+
+// changeLines описывает изменение события по полям: "поле: было → стало".
+func changeLines(ch calendar.Change) []string {
+	before, after := decodeFields(ch.Before), decodeFields(ch.After)
+	switch {
+	case before == nil:
+		before = map[string]json.RawMessage{}
+	case after == nil:
+		after = map[string]json.RawMessage{}
+	}
+
+	keys := append([]string(nil), eventFields...)
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[k] = true
+	}
+	var extra []string
+	for _, m := range []map[string]json.RawMessage{before, after} {
+		for k := range m {
+			if !known[k] && k != "id" {
+				known[k] = true
+				extra = append(extra, k)
+			}
+		}
+	}
+	sort.Strings(extra)
+	keys = append(keys, extra...)
+
+	var lines []string
+	for _, k := range keys {
+		b, a := before[k], after[k]
+		if bytes.Equal(b, a) {
+			continue
+		}
+		format := fieldValue
+		if k == "reminders" {
+			format = remindersValue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", k, format(b), format(a)))
+	}
+	return lines
+}
+
+func decodeFields(state json.RawMessage) map[string]json.RawMessage {
+	if len(state) == 0 {
+		return nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(state, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func fieldValue(v json.RawMessage) string {
+	if len(v) == 0 {
+		return "—"
+	}
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return datetime.FormatLocal(t)
+		}
+		return fmt.Sprintf("%q", s)
+	}
+	return string(v)
+}
+
+func remindersValue(v json.RawMessage) string {
+	var list []struct {
+		Message string    `json:"message"`
+		At      time.Time `json:"at"`
+	}
+	if err := json.Unmarshal(v, &list); err != nil || len(list) == 0 {
+		return fieldValue(v)
+	}
+	var parts []string
+	for _, r := range list {
+		parts = append(parts, fmt.Sprintf("%q %s", r.Message, datetime.FormatLocal(r.At)))
+	}
+	return strings.Join(parts, "; ")
+}
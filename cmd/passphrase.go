@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/logger"
+	"golang.org/x/term"
+)
+
+var (
+	errPassphraseMismatch = errors.New("пароли не совпадают")
+	errNoTerminal         = errors.New("для ввода пароля нужен терминал")
+)
+
+// ReadPassphrase запрашивает пароль в терминале без эха.
+func ReadPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errNoTerminal
+	}
+	fmt.Print(prompt)
+	data, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения пароля: %w", err)
+	}
+	return string(data), nil
+}
+
+// AskPassphrase возвращает пароль из config.PassphraseEnv или запрашивает его в
+// терминале; для нового пароля — дважды.
+func AskPassphrase(confirm bool) (string, error) {
+	if p, ok := os.LookupEnv(config.PassphraseEnv); ok {
+		return p, nil
+	}
+	if confirm {
+		return readNewPassphrase(ReadPassphrase)
+	}
+	return ReadPassphrase("Пароль: ")
+}
+
+func readNewPassphrase(read func(prompt string) (string, error)) (string, error) {
+	p, err := read("Новый пароль: ")
+	if err != nil {
+		return "", err
+	}
+	again, err := read("Повторите новый пароль: ")
+	if err != nil {
+		return "", err
+	}
+	if p != again {
+		return "", errPassphraseMismatch
+	}
+	return p, nil
+}
+
+func (c *Cmd) handlePasswd() {
+	logger.Info("Обработка команды passwd")
+	current, err := c.readPassphrase("Текущий пароль: ")
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка чтения пароля: " + err.Error())
+		return
+	}
+	next, err := readNewPassphrase(c.readPassphrase)
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка чтения пароля: " + err.Error())
+		return
+	}
+
+	if err := c.calendar.ChangePassphrase(current, next); err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка смены пароля: " + err.Error())
+		return
+	}
+	c.outputLn("Пароль изменён")
+	logger.Info("Пароль календаря изменён")
+}
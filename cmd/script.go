@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const sourceFormat = "source <файл|-> [--continue]"
+
+var errScriptDepth = errors.New("слишком глубокая вложенность source")
+
+type scriptFailure struct {
+	line int
+	err  error
+}
+
+type scriptSummary struct {
+	succeeded int
+	failures  []scriptFailure
+	stopped   int
+	firstErr  error
+}
+
+// runScript выполняет строки скрипта через executor. Пустые строки и строки,
+// начинающиеся с #, пропускаются. Ошибки с номерами строк собираются в summary.failures.
+// Без continueOnError выполнение прекращается на первой ошибке; номер строки
+// сохраняется в summary.stopped.
+func (c *Cmd) runScript(r io.Reader, name string, continueOnError bool) (scriptSummary, error) {
+	var sum scriptSummary
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fmt.Fprintln(c.out, config.PromptPrefix+line)
+		c.lastErr = nil
+		c.executor(line)
+		if c.lastErr == nil {
+			sum.succeeded++
+		} else {
+			sum.failures = append(sum.failures, scriptFailure{line: lineNo, err: c.lastErr})
+			c.outputLn(fmt.Sprintf("%s:%d: ошибка в строке", name, lineNo))
+			logger.Error(fmt.Sprintf("Ошибка в строке %s:%d: %v", name, lineNo, c.lastErr))
+			if sum.firstErr == nil {
+				sum.firstErr = fmt.Errorf("%s:%d: %w", name, lineNo, c.lastErr)
+			}
+			if !continueOnError {
+				sum.stopped = lineNo
+				break
+			}
+		}
+		if c.exiting {
+			break
+		}
+	}
+	return sum, scanner.Err()
+}
+
+func (c *Cmd) handleSource(parts []string) {
+	logger.Info("Обработка команды source")
+	if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "--continue") {
+		c.usage(sourceFormat)
+		logger.Error("Неверный формат команды source")
+		return
+	}
+	if c.sourceDepth >= config.ScriptMaxDepth {
+		c.fail("Ошибка", errScriptDepth)
+		return
+	}
+
+	name := parts[1]
+	continueOnError := len(parts) == 3
+
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			c.fail("Ошибка", err)
+			logger.Error("Ошибка открытия скрипта: " + err.Error())
+			return
+		}
+		defer f.Close()
+		r = f
+	}
+
+	c.sourceDepth++
+	sum, err := c.runScript(r, name, continueOnError)
+	c.sourceDepth--
+
+	result := fmt.Sprintf("Скрипт %s: успешно %d, с ошибками %d", name, sum.succeeded, len(sum.failures))
+	if sum.stopped > 0 {
+		result += fmt.Sprintf(", остановлен на строке %d", sum.stopped)
+	}
+	c.outputLn(result)
+	// В пакетном режиме ошибки команд не выводятся, поэтому итог перечисляет их все.
+	for _, f := range sum.failures {
+		c.outputLn(fmt.Sprintf("  строка %d: %v", f.line, f.err))
+	}
+	logger.Info(result)
+
+	c.lastErr = nil
+	if err != nil {
+		c.fail("Ошибка чтения скрипта", err)
+	} else if sum.firstErr != nil {
+		c.lastErr = sum.firstErr
+	}
+}
+
+// ExecScript выполняет команды из файла (или stdin для "-") как source, сохраняет
+// данные и возвращает код завершения первой ошибки.
+func (c *Cmd) ExecScript(name string, continueOnError bool) int {
+	logger.Info(fmt.Sprintf("Выполнение скрипта %s", name))
+	args := []string{"source", name}
+	if continueOnError {
+		args = append(args, "--continue")
+	}
+
+	c.dispatch(args)
+	err := c.lastErr
+	if c.shutdown() != exitOK && err == nil {
+		err = c.lastErr
+	}
+	if err != nil {
+		_, code := classifyError(err)
+		return code
+	}
+	return exitOK
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestRunScript(t *testing.T) {
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	script := strings.Join([]string{
+		"# комментарий",
+		`add "Первое" "` + start + `" low`,
+		"",
+		"remove нет-такого",
+		`add "Второе" "` + start + `" low`,
+	}, "\n")
+
+	for _, continueOnError := range []bool{false, true} {
+		cal := calendar.NewCalendar(storage.NewJsonStorage(filepath.Join(t.TempDir(), "calendar.json")))
+		cal.DisableReminders()
+		c := NewCmd(cal)
+		c.out = io.Discard
+
+		sum, err := c.runScript(strings.NewReader(script), "test", continueOnError)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantOK, wantStopped := 1, 4
+		if continueOnError {
+			wantOK, wantStopped = 2, 0
+		}
+		if sum.succeeded != wantOK || len(sum.failures) != 1 || sum.failures[0].line != 4 || sum.stopped != wantStopped {
+			t.Errorf("Ожидали %d успешных, ошибку в строке 4 и остановку на строке %d (continue=%v), получили: %+v", wantOK, wantStopped, continueOnError, sum)
+		}
+		if sum.firstErr == nil || !strings.HasPrefix(sum.firstErr.Error(), "test:4:") {
+			t.Errorf("Ожидали первую ошибку в строке test:4 (continue=%v), получили: %v", continueOnError, sum.firstErr)
+		}
+		if n := len(cal.GetEvents()); n != wantOK {
+			t.Errorf("Ожидали %d событий (continue=%v), получили: %d", wantOK, continueOnError, n)
+		}
+		cal.Close()
+	}
+}
+
+func TestSourceSummaryListsFailures(t *testing.T) {
+	dir := t.TempDir()
+	start := datetime.FormatLocal(time.Now().Add(48 * time.Hour))
+	script := filepath.Join(dir, "script.txt")
+	lines := strings.Join([]string{
+		"remove нет-такого",
+		`add "Встреча" "` + start + `" low`,
+		`add "Встреча" "завтра" low`,
+	}, "\n")
+	if err := os.WriteFile(script, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cal := calendar.NewCalendar(storage.NewJsonStorage(filepath.Join(dir, "calendar.json")))
+	cal.DisableReminders()
+	t.Cleanup(cal.Close)
+	var out strings.Builder
+	c := NewCmd(cal)
+	c.out = &out
+	c.batch = true
+	c.dispatch([]string{"source", script, "--continue"})
+
+	summary := out.String()
+	for _, want := range []string{
+		"успешно 1, с ошибками 2",
+		"  строка 1: id=\"нет-такого\": " + calendar.ErrEventNotFound.Error(),
+		"  строка 3: ",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Ожидали %q в итоге скрипта, получили:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+// signalParser оборачивает парсер ввода go-prompt. go-prompt сам ловит SIGINT/SIGTERM,
+// восстанавливает терминал через TearDown и сразу вызывает os.Exit. Поэтому у парсера
+// свой канал сигналов: если к моменту TearDown сигнал уже пришёл, завершение с
+// сохранением данных выполняется прямо в TearDown, и go-prompt до os.Exit не доходит.
+type signalParser struct {
+	prompt.ConsoleParser
+	signals chan os.Signal
+	stop    func(os.Signal)
+}
+
+func newSignalParser(stop func(os.Signal)) *signalParser {
+	p := &signalParser{
+		ConsoleParser: prompt.NewStandardInputParser(),
+		signals:       make(chan os.Signal, 1),
+		stop:          stop,
+	}
+	signal.Notify(p.signals, os.Interrupt, syscall.SIGTERM)
+	return p
+}
+
+func (p *signalParser) TearDown() error {
+	err := p.ConsoleParser.TearDown()
+	select {
+	case sig := <-p.signals:
+		p.stop(sig)
+	default:
+	}
+	return err
+}
+
+// handleSignals завершает приложение по сигналу, пока go-prompt не читает ввод,
+// например во время выполнения команды.
+func (c *Cmd) handleSignals() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		c.stopOnSignal(<-sigCh)
+	}()
+}
+
+// stopOnSignal сохраняет данные и завершает процесс. Выполняется один раз: второй
+// вызывающий (обработчик сигналов или TearDown) ждёт, пока первый завершит процесс.
+func (c *Cmd) stopOnSignal(sig os.Signal) {
+	c.signalOnce.Do(func() {
+		logger.Info(fmt.Sprintf("Получен сигнал %v", sig))
+
+		code := c.shutdown()
+		if s, ok := sig.(syscall.Signal); ok && code == exitOK {
+			code = 128 + int(s)
+		}
+		logger.Close()
+		c.exit(code)
+	})
+}
+
+func (c *Cmd) exitChecker(_ string, breakline bool) bool {
+	return breakline && c.exiting
+}
+
+// shutdown останавливает напоминания, сохраняет данные, закрывает хранилище и канал
+// уведомлений и дожидается вывода оставшихся уведомлений. Повторные вызовы возвращают
+// код первого.
+func (c *Cmd) shutdown() int {
+	c.shutdownOnce.Do(func() {
+		c.exitCode = exitOK
+		c.calendar.StopReminders()
+
+		if err := c.calendar.Save(); err != nil {
+			c.fail("Ошибка сохранения данных", err)
+			logger.Error("Ошибка сохранения данных: " + err.Error())
+			c.exitCode = exitError
+		}
+		if c.closer != nil {
+			if err := c.closer.Close(); err != nil {
+				c.fail("Ошибка закрытия хранилища", err)
+				logger.Error("Ошибка закрытия хранилища: " + err.Error())
+				c.exitCode = exitError
+			}
+		}
+
+		c.calendar.Close()
+		c.wg.Wait()
+		logger.Info(fmt.Sprintf("Приложение завершило работу, код %d", c.exitCode))
+	})
+	return c.exitCode
+}
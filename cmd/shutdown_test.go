@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/calendar"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+type closeCounter struct {
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestStopOnSignalClosesStorage(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calendar.json")
+	cal := calendar.NewCalendar(storage.NewJsonStorage(filename))
+	cal.DisableReminders()
+	if err := cal.Load(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := cal.AddEvent("Встреча", datetime.FormatLocal(time.Now().Add(48*time.Hour)), "", events.PriorityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCmd(cal)
+	c.out = io.Discard
+	closer := &closeCounter{}
+	c.SetCloser(closer)
+	var codes []int
+	c.exit = func(code int) { codes = append(codes, code) }
+
+	c.stopOnSignal(syscall.SIGTERM)
+	c.stopOnSignal(syscall.SIGINT)
+
+	if len(codes) != 1 || codes[0] != 128+int(syscall.SIGTERM) {
+		t.Errorf("Ожидали один выход с кодом %d, получили: %v", 128+int(syscall.SIGTERM), codes)
+	}
+	if closer.closed != 1 {
+		t.Errorf("Ожидали, что хранилище закроется один раз, закрыто %d раз", closer.closed)
+	}
+
+	saved := calendar.NewCalendar(storage.NewJsonStorage(filename))
+	saved.DisableReminders()
+	if err := saved.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := saved.GetEvent(e.ID); err != nil {
+		t.Errorf("Ожидали сохранённое событие, получили: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+const (
+	restoreFormat    = "restore <ID>"
+	trashEmptyFormat = "trash-empty [--older-than 30d]"
+)
+
+func (c *Cmd) handleTrash() {
+	logger.Info("Обработка команды trash")
+	list := c.calendar.Trash()
+	if len(list) == 0 {
+		c.outputLn("Корзина пуста")
+		logger.Info("Корзина пуста")
+		return
+	}
+	for _, e := range list {
+		c.outputLn(fmt.Sprintf("%-*s \"%s\" %s, удалено %s", config.AgendaIDWidth, shortID(e.ID), e.Title,
+			datetime.FormatRange(e.StartAt, e.EndAt, e.AllDay), datetime.FormatLocal(e.DeletedAt)))
+	}
+	c.outputLn(fmt.Sprintf("В корзине событий: %d", len(list)))
+	logger.Info(fmt.Sprintf("В корзине %d событий", len(list)))
+}
+
+func (c *Cmd) handleRestore(parts []string) {
+	logger.Info("Обработка команды restore")
+	if len(parts) != 2 {
+		c.usage(restoreFormat)
+		logger.Error("Неверный формат команды restore")
+		return
+	}
+
+	e, err := c.calendar.RestoreEvent(parts[1])
+	if err != nil {
+		c.fail("Ошибка", err)
+		logger.Error("Ошибка восстановления события: " + err.Error())
+		return
+	}
+	c.outputLn(fmt.Sprintf("Событие восстановлено: \"%s\", ID: %s", e.Title, e.ID))
+	c.warnConflicts(e.ID)
+}
+
+func (c *Cmd) handleTrashEmpty(parts []string) {
+	logger.Info("Обработка команды trash-empty")
+	olderThan := time.Duration(0)
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 3 && parts[1] == "--older-than":
+		d, err := datetime.ParseDuration(parts[2])
+		if err != nil || d < 0 {
+			c.fail("Ошибка", &usageError{format: trashEmptyFormat, cause: fmt.Errorf("--older-than: неверная длительность %q", parts[2])})
+			if !c.batch {
+				c.outputLn("Формат: " + trashEmptyFormat)
+			}
+			logger.Error("Неверный формат команды trash-empty")
+			return
+		}
+		olderThan = d
+	default:
+		c.usage(trashEmptyFormat)
+		logger.Error("Неверный формат команды trash-empty")
+		return
+	}
+
+	n := c.calendar.EmptyTrash(olderThan)
+	c.outputLn(fmt.Sprintf("Окончательно удалено событий: %d", n))
+}
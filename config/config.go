@@ -1,20 +1,92 @@
 package config
 
-const (
-	DataDir         = "data/"
-	DataFileName    = DataDir + "calendar.json"
-	LogFileName     = DataDir + "app.log"
-	LogArchiveName  = DataDir + "console-log.zip"
-	ZipLogEntryName = "console.log"
+import "time"
+
+// Значения, которые можно переопределить файлом настроек, переменными окружения
+// и флагами командной строки (см. settings.go). По умолчанию данные хранятся в
+// XDG-каталоге данных, логи — в XDG-каталоге состояния (см. dirs.go).
+var (
+	DataDir  = DefaultDataDir()
+	StateDir = DefaultStateDir()
+
+	// Пути к файлам выводятся из DataDir и StateDir в resolvePaths.
+	DataFileName    string
+	SqliteFileName  string
+	JournalFileName string
+	LogFileName     string
+	LogArchiveName  string
 
 	PromptPrefix         = "> "
 	PromptMaxSuggestions = 3
 
 	ListColWidthID     = 37
 	ListColWidthTitle  = 51
-	ListColWidthDate   = 17
+	ListColWidthDate   = 37
 	ListColWidthStatus = 7
-	ListTitlePad       = 50
+	// ListColWidthCalendar — ширина колонки календаря; колонка выводится, если календарей больше одного.
+	ListColWidthCalendar = 15
+	ListTitlePad         = 50
+	ListPageSize         = 20
+
+	// При StrictConflicts пересекающиеся события не добавляются и не переносятся.
+	StrictConflicts = false
 
 	PrettyJSON = true
+
+	// HistoryLimit — сколько последних записей показывает history без ID.
+	HistoryLimit = 20
+
+	// StorageBackend — хранилище по умолчанию: StorageJSON переписывает файл целиком,
+	// StorageSQLite сохраняет только изменённые события.
+	StorageBackend = StorageJSON
+
+	// AutosaveDelay откладывает автосохранение после изменения, объединяя серию
+	// изменений в одну запись; 0 — сохранять сразу.
+	Autosave      = true
+	AutosaveDelay = 0 * time.Second
+
+	MissedRemindersPolicy = MissedRemindersSummary
+
+	// DefaultEventDuration — длительность события без окончания при поиске пересечений
+	// и свободного времени.
+	DefaultEventDuration = time.Hour
+
+	// Поиск свободного времени: рабочие часы по умолчанию и горизонт без --to.
+	WorkDayStart      = 9 * time.Hour
+	WorkDayEnd        = 18 * time.Hour
+	FreeSearchHorizon = 7 * 24 * time.Hour
 )
+
+const (
+	AppName = "calendarOfEvents"
+
+	ZipLogEntryName = "console.log"
+
+	ScriptMaxDepth = 8
+
+	AgendaTimeWidth = 11
+	AgendaIDWidth   = 8
+
+	RecurrenceListHorizon = 30 * 24 * time.Hour
+
+	// Поиск свободного времени: шаг выравнивания начала слота и число выводимых слотов.
+	FreeSlotStep   = 15 * time.Minute
+	FreeSlotsLimit = 10
+
+	// HistorySummaryEvents — сколько событий перечисляется в строке записи history.
+	HistorySummaryEvents = 3
+
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+
+	// PassphraseEnv — переменная окружения с паролем зашифрованного календаря
+	// для запуска без терминала.
+	PassphraseEnv = "CALENDAR_PASSPHRASE"
+
+	MissedRemindersFire    = "fire"
+	MissedRemindersSummary = "summary"
+)
+
+// AppVersion записывается в файл данных; при сборке задаётся через
+// -ldflags "-X github.com/leksusdev/calendarOfEvents/config.AppVersion=...".
+var AppVersion = "dev"
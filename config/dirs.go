@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// ProfileEnv — переменная окружения с именем профиля; флаг --profile имеет
+	// приоритет над ней.
+	ProfileEnv     = "CALENDAR_PROFILE"
+	DefaultProfile = "default"
+
+	// LegacyDataDir — каталог данных прежних версий относительно рабочего каталога.
+	LegacyDataDir = "data"
+)
+
+var ErrInvalidProfile = errors.New("недопустимое имя профиля")
+
+var profileRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Profile — текущий профиль. У каждого профиля свои файл настроек, календарь,
+// журнал и логи; профиль по умолчанию хранится в корне каталогов приложения.
+var Profile = DefaultProfile
+
+// SetProfile выбирает профиль; пустое имя означает $CALENDAR_PROFILE или профиль
+// по умолчанию. Вызывается до Load.
+func SetProfile(name string) error {
+	if name == "" {
+		name = os.Getenv(ProfileEnv)
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+	if !profileRe.MatchString(name) {
+		return fmt.Errorf("%w %q: допустимы строчные латинские буквы, цифры, - и _", ErrInvalidProfile, name)
+	}
+	Profile = name
+	return nil
+}
+
+// DefaultPath возвращает путь к файлу настроек профиля в XDG-каталоге конфигурации.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(profileDir(dir), "config.toml")
+}
+
+// DefaultDataDir возвращает каталог данных профиля: $XDG_DATA_HOME/calendarOfEvents
+// или ~/.local/share/calendarOfEvents.
+func DefaultDataDir() string {
+	return profileDir(xdgDir("XDG_DATA_HOME", ".local/share"))
+}
+
+// DefaultStateDir возвращает каталог логов профиля: $XDG_STATE_HOME/calendarOfEvents
+// или ~/.local/state/calendarOfEvents.
+func DefaultStateDir() string {
+	return profileDir(xdgDir("XDG_STATE_HOME", ".local/state"))
+}
+
+// xdgDir возвращает каталог из переменной env или home/fallback. По спецификации
+// XDG относительный путь в переменной игнорируется; без домашнего каталога
+// используется LegacyDataDir в рабочем каталоге.
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return LegacyDataDir
+	}
+	return filepath.Join(home, fallback)
+}
+
+func profileDir(base string) string {
+	if Profile == DefaultProfile {
+		return filepath.Join(base, AppName)
+	}
+	return filepath.Join(base, AppName, "profiles", Profile)
+}
+
+// EnsureDirs создаёт каталоги данных и логов.
+func EnsureDirs() error {
+	for _, dir := range []string{DataDir, StateDir, filepath.Dir(LogFileName)} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("ошибка создания каталога %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// LegacyData сообщает о данных прежних версий в ./data, если каталог данных не
+// задан явно, а в новом каталоге календаря ещё нет. Данные не переносятся
+// автоматически, чтобы не потерять их при запуске из другого каталога.
+func LegacyData() (string, bool) {
+	if s, ok := Lookup("data_dir"); !ok || s.Source != SourceDefault || Profile != DefaultProfile {
+		return "", false
+	}
+	if filepath.Clean(DataDir) == LegacyDataDir {
+		return "", false
+	}
+	for _, name := range []string{DataFileName, SqliteFileName} {
+		if _, err := os.Stat(name); err == nil {
+			return "", false
+		}
+	}
+	for _, name := range []string{"calendar.json", "calendar.db"} {
+		legacy := filepath.Join(LegacyDataDir, name)
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy, true
+		}
+	}
+	return "", false
+}
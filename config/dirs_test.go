@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileDirs(t *testing.T) {
+	keepSettings(t)
+	base := t.TempDir()
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(env, filepath.Join(base, env))
+	}
+	t.Setenv(ConfigEnv, "")
+	t.Setenv(ProfileEnv, "")
+
+	tests := []struct {
+		profile string
+		sub     string
+	}{
+		{"", AppName},
+		{DefaultProfile, AppName},
+		{"work", filepath.Join(AppName, "profiles", "work")},
+	}
+	for _, tt := range tests {
+		if err := SetProfile(tt.profile); err != nil {
+			t.Fatal(err)
+		}
+		if err := Load("", nil); err != nil {
+			t.Fatal(err)
+		}
+		paths := [][2]string{
+			{FilePath, filepath.Join(base, "XDG_CONFIG_HOME", tt.sub, "config.toml")},
+			{DataFileName, filepath.Join(base, "XDG_DATA_HOME", tt.sub, "calendar.json")},
+			{LogFileName, filepath.Join(base, "XDG_STATE_HOME", tt.sub, "app.log")},
+		}
+		for _, p := range paths {
+			if p[0] != p[1] {
+				t.Errorf("Ожидали путь %q для профиля %q, получили: %q", p[1], tt.profile, p[0])
+			}
+		}
+	}
+
+	if err := EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{DataDir, StateDir} {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("Ожидали, что EnsureDirs создаст %s, получили: %v", dir, err)
+		}
+	}
+}
+
+func TestProfileFromEnv(t *testing.T) {
+	keepSettings(t)
+	t.Setenv(ProfileEnv, "team-oncall")
+	if err := SetProfile(""); err != nil {
+		t.Fatal(err)
+	}
+	if Profile != "team-oncall" {
+		t.Errorf("Ожидали профиль team-oncall, получили: %q", Profile)
+	}
+	if err := SetProfile("home"); err != nil || Profile != "home" {
+		t.Errorf("Ожидали профиль home из флага, получили: %q, %v", Profile, err)
+	}
+}
+
+func TestInvalidProfile(t *testing.T) {
+	keepSettings(t)
+	for _, name := range []string{"../etc", "Work", "a b", "-x"} {
+		if err := SetProfile(name); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("Ожидали ErrInvalidProfile для %q, получили: %v", name, err)
+		}
+	}
+}
+
+func TestLegacyData(t *testing.T) {
+	keepSettings(t)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	if err := Load(writeConfig(t, ""), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LegacyData(); ok {
+		t.Error("Не ожидали старые данные без ./data")
+	}
+
+	if err := os.MkdirAll(LegacyDataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(LegacyDataDir, "calendar.json"), []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if legacy, ok := LegacyData(); !ok || legacy != filepath.Join(LegacyDataDir, "calendar.json") {
+		t.Errorf("Ожидали старые данные в ./data, получили: %q, %v", legacy, ok)
+	}
+
+	if err := EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(DataFileName, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LegacyData(); ok {
+		t.Error("Не ожидали старые данные, когда новый календарь уже есть")
+	}
+}
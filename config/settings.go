@@ -0,0 +1,416 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigEnv — переменная окружения с путём к файлу настроек; флаг --config
+// имеет приоритет над ней.
+const ConfigEnv = "CALENDAR_CONFIG"
+
+// envPrefix добавляется к имени настройки в переменной окружения:
+// list.page_size читается из CALENDAR_LIST_PAGE_SIZE.
+const envPrefix = "CALENDAR_"
+
+var (
+	ErrUnknownSetting = errors.New("неизвестная настройка")
+	ErrInvalidSetting = errors.New("недопустимое значение настройки")
+)
+
+// Source — откуда взято действующее значение настройки.
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceFile
+	SourceEnv
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceFile:
+		return "файл"
+	case SourceEnv:
+		return "окружение"
+	case SourceFlag:
+		return "флаг"
+	default:
+		return "по умолчанию"
+	}
+}
+
+// Setting описывает одну настраиваемую переменную пакета. Key задаёт имя в файле
+// настроек (секция и ключ через точку), из него выводятся имена флага и
+// переменной окружения.
+type Setting struct {
+	Key   string
+	Usage string
+
+	Source Source
+	// Origin уточняет источник: путь к файлу, имя переменной окружения или флага.
+	Origin string
+
+	value value
+}
+
+type value interface {
+	String() string
+	Set(string) error
+}
+
+// Value возвращает действующее значение в том виде, в котором его можно задать.
+func (s *Setting) Value() string {
+	return s.value.String()
+}
+
+// Flag возвращает имя флага командной строки: list.page_size → list-page-size.
+func (s *Setting) Flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.Key)
+}
+
+// Env возвращает имя переменной окружения: list.page_size → CALENDAR_LIST_PAGE_SIZE.
+func (s *Setting) Env() string {
+	return envPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(s.Key))
+}
+
+func (s *Setting) set(raw string, source Source, origin string) error {
+	if err := s.value.Set(raw); err != nil {
+		return fmt.Errorf("%s: %w", s.Key, err)
+	}
+	s.Source = source
+	s.Origin = origin
+	return nil
+}
+
+var settings = []*Setting{
+	{Key: "data_dir", Usage: "каталог данных календаря", value: &stringValue{p: &DataDir, required: true}},
+	{Key: "storage", Usage: "хранилище: json или sqlite", value: &enumValue{p: &StorageBackend, allowed: []string{StorageJSON, StorageSQLite}}},
+	{Key: "state_dir", Usage: "каталог логов и архивов", value: &stringValue{p: &StateDir, required: true}},
+	{Key: "log_file", Usage: "файл лога приложения (по умолчанию app.log в каталоге логов)", value: &stringValue{p: &LogFileName, required: true}},
+	{Key: "pretty_json", Usage: "сохранять JSON с отступами", value: &boolValue{p: &PrettyJSON}},
+	{Key: "autosave", Usage: "сохранять данные после каждого изменения", value: &boolValue{p: &Autosave}},
+	{Key: "autosave_delay", Usage: "задержка автосохранения, например 2s", value: &durationValue{p: &AutosaveDelay}},
+	{Key: "strict_conflicts", Usage: "запрещать пересекающиеся события", value: &boolValue{p: &StrictConflicts}},
+	{Key: "default_event_duration", Usage: "длительность события без окончания при поиске пересечений и свободного времени, например 1h", value: &durationValue{p: &DefaultEventDuration, positive: true}},
+	{Key: "missed_reminders", Usage: "пропущенные напоминания: fire или summary", value: &enumValue{p: &MissedRemindersPolicy, allowed: []string{MissedRemindersFire, MissedRemindersSummary}}},
+	{Key: "prompt.prefix", Usage: "приглашение командной оболочки", value: &stringValue{p: &PromptPrefix}},
+	{Key: "prompt.max_suggestions", Usage: "число подсказок автодополнения", value: &intValue{p: &PromptMaxSuggestions, min: 1, max: 100}},
+	{Key: "list.width_id", Usage: "ширина колонки ID в list", value: &intValue{p: &ListColWidthID, min: 1}},
+	{Key: "list.width_title", Usage: "ширина колонки названия в list", value: &intValue{p: &ListColWidthTitle, min: 1}},
+	{Key: "list.width_date", Usage: "ширина колонки даты в list", value: &intValue{p: &ListColWidthDate, min: 1}},
+	{Key: "list.width_status", Usage: "ширина колонки приоритета в list", value: &intValue{p: &ListColWidthStatus, min: 1}},
+	{Key: "list.width_calendar", Usage: "ширина колонки календаря в list", value: &intValue{p: &ListColWidthCalendar, min: 1}},
+	{Key: "list.title_pad", Usage: "длина названия с точками-заполнителями в list", value: &intValue{p: &ListTitlePad, min: 0}},
+	{Key: "list.page_size", Usage: "размер страницы list", value: &intValue{p: &ListPageSize, min: 1}},
+	{Key: "history.limit", Usage: "число записей history без ID", value: &intValue{p: &HistoryLimit, min: 1}},
+	{Key: "free.work_start", Usage: "начало рабочего дня для free, например 9:00", value: &clockValue{p: &WorkDayStart}},
+	{Key: "free.work_end", Usage: "конец рабочего дня для free, например 18:00", value: &clockValue{p: &WorkDayEnd}},
+	{Key: "free.horizon", Usage: "период поиска free без --to, например 168h", value: &durationValue{p: &FreeSearchHorizon, positive: true}},
+}
+
+var (
+	// FilePath — файл настроек, который читался при загрузке.
+	FilePath string
+	// FileFound сообщает, был ли файл настроек найден.
+	FileFound bool
+)
+
+// Settings возвращает все настройки в порядке объявления.
+func Settings() []*Setting {
+	return settings
+}
+
+// Lookup находит настройку по ключу файла настроек.
+func Lookup(key string) (*Setting, bool) {
+	i := slices.IndexFunc(settings, func(s *Setting) bool { return s.Key == key })
+	if i < 0 {
+		return nil, false
+	}
+	return settings[i], true
+}
+
+// RegisterFlags добавляет в flags флаг для каждой настройки. Значения, заданные
+// флагами, не переопределяются файлом и окружением при Load.
+func RegisterFlags(flags *flag.FlagSet) {
+	for _, s := range settings {
+		flags.Var(flagValue{s}, s.Flag(), s.Usage)
+	}
+}
+
+type flagValue struct{ s *Setting }
+
+func (f flagValue) String() string {
+	if f.s == nil {
+		return ""
+	}
+	return f.s.Value()
+}
+
+func (f flagValue) Set(raw string) error {
+	return f.s.value.Set(raw) // ключ в ошибке не нужен: пакет flag сам называет флаг
+}
+
+func (f flagValue) IsBoolFlag() bool {
+	_, ok := f.s.value.(*boolValue)
+	return ok
+}
+
+// Load применяет настройки по возрастанию приоритета: файл, переменные окружения,
+// уже разобранные флаги; затем выводит пути к файлам данных и проверяет значения.
+// Пустой path означает $CALENDAR_CONFIG или DefaultPath; отсутствие файла по
+// умолчанию не считается ошибкой.
+func Load(path string, flags *flag.FlagSet) error {
+	if flags != nil {
+		flags.Visit(func(f *flag.Flag) {
+			if v, ok := f.Value.(flagValue); ok {
+				v.s.Source = SourceFlag
+				v.s.Origin = "--" + f.Name
+			}
+		})
+	}
+
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+	FilePath, FileFound = path, false
+	if path != "" {
+		if err := loadFile(path); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) || explicit {
+				return err
+			}
+		} else {
+			FileFound = true
+		}
+	}
+
+	if err := loadEnv(); err != nil {
+		return err
+	}
+	resolvePaths()
+	return Validate()
+}
+
+func loadFile(path string) error {
+	var raw map[string]any
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		return fmt.Errorf("ошибка чтения файла настроек: %w", err)
+	}
+	values := make(map[string]string)
+	if err := flatten("", raw, values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s, ok := Lookup(key)
+		if !ok {
+			return fmt.Errorf("%s: %w %q", path, ErrUnknownSetting, key)
+		}
+		if s.Source == SourceFlag {
+			continue
+		}
+		if err := s.set(values[key], SourceFile, path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// flatten превращает таблицы TOML в ключи через точку: [list] page_size → list.page_size.
+func flatten(prefix string, raw map[string]any, out map[string]string) error {
+	for k, v := range raw {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(key+".", v, out); err != nil {
+				return err
+			}
+		case string:
+			out[key] = v
+		case int64, bool:
+			out[key] = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("%s: %w: неподдерживаемый тип %T", key, ErrInvalidSetting, v)
+		}
+	}
+	return nil
+}
+
+func loadEnv() error {
+	for _, s := range settings {
+		if s.Source == SourceFlag {
+			continue
+		}
+		raw, ok := os.LookupEnv(s.Env())
+		if !ok {
+			continue
+		}
+		if err := s.set(raw, SourceEnv, s.Env()); err != nil {
+			return fmt.Errorf("переменная %s: %w", s.Env(), err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	resolvePaths()
+}
+
+// resolvePaths выводит пути к файлам из DataDir и StateDir. Каталоги и файл лога,
+// не заданные явно, следуют за текущим профилем.
+func resolvePaths() {
+	if s, ok := Lookup("data_dir"); !ok || s.Source == SourceDefault {
+		DataDir = DefaultDataDir()
+	}
+	if s, ok := Lookup("state_dir"); !ok || s.Source == SourceDefault {
+		StateDir = DefaultStateDir()
+	}
+	DataFileName = filepath.Join(DataDir, "calendar.json")
+	SqliteFileName = filepath.Join(DataDir, "calendar.db")
+	JournalFileName = filepath.Join(DataDir, "journal.jsonl")
+	LogArchiveName = filepath.Join(StateDir, "console-log.zip")
+	if s, ok := Lookup("log_file"); !ok || s.Source == SourceDefault {
+		LogFileName = filepath.Join(StateDir, "app.log")
+	}
+}
+
+// Validate проверяет согласованность настроек между собой.
+func Validate() error {
+	if ListTitlePad > ListColWidthTitle {
+		return fmt.Errorf("list.title_pad: %w: %d больше list.width_title %d", ErrInvalidSetting, ListTitlePad, ListColWidthTitle)
+	}
+	if WorkDayStart >= WorkDayEnd {
+		return fmt.Errorf("free.work_start: %w: %s не раньше free.work_end %s", ErrInvalidSetting, formatClock(WorkDayStart), formatClock(WorkDayEnd))
+	}
+	return nil
+}
+
+type stringValue struct {
+	p        *string
+	required bool
+}
+
+func (v *stringValue) String() string { return *v.p }
+
+func (v *stringValue) Set(raw string) error {
+	if v.required && strings.TrimSpace(raw) == "" {
+		return fmt.Errorf("%w: пустое значение", ErrInvalidSetting)
+	}
+	*v.p = raw
+	return nil
+}
+
+type enumValue struct {
+	p       *string
+	allowed []string
+}
+
+func (v *enumValue) String() string { return *v.p }
+
+func (v *enumValue) Set(raw string) error {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if !slices.Contains(v.allowed, raw) {
+		return fmt.Errorf("%w %q, допустимо: %s", ErrInvalidSetting, raw, strings.Join(v.allowed, ", "))
+	}
+	*v.p = raw
+	return nil
+}
+
+// intValue ограничивает число снизу min и, если max больше нуля, сверху max.
+type intValue struct {
+	p        *int
+	min, max int
+}
+
+func (v *intValue) String() string { return strconv.Itoa(*v.p) }
+
+func (v *intValue) Set(raw string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("%w %q: ожидается целое число", ErrInvalidSetting, raw)
+	}
+	if n < v.min {
+		return fmt.Errorf("%w %d: минимум %d", ErrInvalidSetting, n, v.min)
+	}
+	if v.max > 0 && n > v.max {
+		return fmt.Errorf("%w %d: максимум %d", ErrInvalidSetting, n, v.max)
+	}
+	*v.p = n
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v *boolValue) String() string { return strconv.FormatBool(*v.p) }
+
+func (v *boolValue) Set(raw string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return fmt.Errorf("%w %q: ожидается true или false", ErrInvalidSetting, raw)
+	}
+	*v.p = b
+	return nil
+}
+
+// durationValue принимает неотрицательную длительность; при positive — только больше нуля.
+type durationValue struct {
+	p        *time.Duration
+	positive bool
+}
+
+func (v *durationValue) String() string { return v.p.String() }
+
+func (v *durationValue) Set(raw string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || d < 0 {
+		return fmt.Errorf("%w %q: ожидается неотрицательная длительность, например 2s", ErrInvalidSetting, raw)
+	}
+	if v.positive && d == 0 {
+		return fmt.Errorf("%w %q: ожидается положительная длительность, например 1h", ErrInvalidSetting, raw)
+	}
+	*v.p = d
+	return nil
+}
+
+// clockValue — время суток в виде ЧЧ:ММ от 0:00 до 24:00, хранится как смещение от полуночи.
+type clockValue struct{ p *time.Duration }
+
+func (v *clockValue) String() string { return formatClock(*v.p) }
+
+func (v *clockValue) Set(raw string) error {
+	hh, mm, hasMinutes := strings.Cut(strings.TrimSpace(raw), ":")
+	h, err := strconv.Atoi(hh)
+	m := 0
+	if err == nil && hasMinutes {
+		m, err = strconv.Atoi(mm)
+	}
+	if err != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m > 0) {
+		return fmt.Errorf("%w %q: ожидается время от 0:00 до 24:00, например 9:00", ErrInvalidSetting, raw)
+	}
+	*v.p = time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	return nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
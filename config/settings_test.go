@@ -0,0 +1,195 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// keepSettings восстанавливает значения пакета после теста.
+func keepSettings(t *testing.T) {
+	t.Helper()
+	values := make([]string, len(settings))
+	for i, s := range settings {
+		values[i] = s.Value()
+	}
+	profile := Profile
+	t.Cleanup(func() {
+		for i, s := range settings {
+			if err := s.value.Set(values[i]); err != nil {
+				t.Fatal(err)
+			}
+			s.Source, s.Origin = SourceDefault, ""
+		}
+		Profile = profile
+		FilePath, FileFound = "", false
+		resolvePaths()
+	})
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	keepSettings(t)
+	dir := t.TempDir()
+	path := writeConfig(t, `
+data_dir = "`+dir+`"
+pretty_json = false
+autosave_delay = "2s"
+
+[list]
+page_size = 50
+width_id = 40
+
+[prompt]
+prefix = "cal> "
+`)
+	t.Setenv("CALENDAR_LIST_PAGE_SIZE", "30")
+	t.Setenv("CALENDAR_PROMPT_PREFIX", "env> ")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(flags)
+	if err := flags.Parse([]string{"--prompt-prefix", "flag> ", "--storage", "sqlite"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(path, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if !FileFound || FilePath != path {
+		t.Errorf("Ожидали найденный файл %q, получили: %q (найден: %v)", path, FilePath, FileFound)
+	}
+	if PrettyJSON || AutosaveDelay != 2*time.Second || ListColWidthID != 40 {
+		t.Errorf("Ожидали значения из файла, получили: pretty %v, delay %s, width %d", PrettyJSON, AutosaveDelay, ListColWidthID)
+	}
+	if ListPageSize != 30 {
+		t.Errorf("Ожидали ListPageSize 30 из окружения, получили: %d", ListPageSize)
+	}
+	if PromptPrefix != "flag> " || StorageBackend != StorageSQLite {
+		t.Errorf("Ожидали значения из флагов, получили: prefix %q, storage %q", PromptPrefix, StorageBackend)
+	}
+	if DataFileName != filepath.Join(dir, "calendar.json") || JournalFileName != filepath.Join(dir, "journal.jsonl") {
+		t.Errorf("Ожидали пути внутри data_dir, получили: %q, %q", DataFileName, JournalFileName)
+	}
+
+	sources := map[string]Source{
+		"data_dir":       SourceFile,
+		"list.page_size": SourceEnv,
+		"prompt.prefix":  SourceFlag,
+		"storage":        SourceFlag,
+		"history.limit":  SourceDefault,
+	}
+	for key, want := range sources {
+		s, _ := Lookup(key)
+		if s.Source != want {
+			t.Errorf("Ожидали источник %s для %s, получили: %s", want, key, s.Source)
+		}
+	}
+	if s, _ := Lookup("list.page_size"); s.Origin != "CALENDAR_LIST_PAGE_SIZE" {
+		t.Errorf("Ожидали происхождение CALENDAR_LIST_PAGE_SIZE, получили: %q", s.Origin)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	keepSettings(t)
+	missing := filepath.Join(t.TempDir(), "absent.toml")
+
+	t.Setenv(ConfigEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := Load("", nil); err != nil {
+		t.Fatalf("Не ожидали ошибку без файла по умолчанию: %v", err)
+	}
+	if FileFound {
+		t.Error("Не ожидали FileFound для отсутствующего файла по умолчанию")
+	}
+
+	if err := Load(missing, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Ожидали ErrNotExist для явно указанного файла, получили: %v", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		want    error
+	}{
+		{"unknown key", "colour = \"red\"\n", nil, ErrUnknownSetting},
+		{"unknown section key", "[list]\nheight = 3\n", nil, ErrUnknownSetting},
+		{"negative int", "[list]\npage_size = 0\n", nil, ErrInvalidSetting},
+		{"wrong type", "pretty_json = \"maybe\"\n", nil, ErrInvalidSetting},
+		{"enum", "storage = \"mysql\"\n", nil, ErrInvalidSetting},
+		{"float", "[list]\npage_size = 2.5\n", nil, ErrInvalidSetting},
+		{"empty data dir", "data_dir = \" \"\n", nil, ErrInvalidSetting},
+		{"cross check", "[list]\ntitle_pad = 60\nwidth_title = 51\n", nil, ErrInvalidSetting},
+		{"env", "", map[string]string{"CALENDAR_AUTOSAVE_DELAY": "soon"}, ErrInvalidSetting},
+		{"zero duration", "default_event_duration = \"0s\"\n", nil, ErrInvalidSetting},
+		{"clock", "[free]\nwork_start = \"25:00\"\n", nil, ErrInvalidSetting},
+		{"work hours", "[free]\nwork_start = \"18:00\"\nwork_end = \"9:00\"\n", nil, ErrInvalidSetting},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepSettings(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if err := Load(writeConfig(t, tt.content), nil); !errors.Is(err, tt.want) {
+				t.Errorf("Ожидали ошибку %v, получили: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadEventDuration(t *testing.T) {
+	keepSettings(t)
+	t.Setenv("CALENDAR_DEFAULT_EVENT_DURATION", "30m")
+	if err := Load(writeConfig(t, ""), nil); err != nil {
+		t.Fatal(err)
+	}
+	if DefaultEventDuration != 30*time.Minute {
+		t.Errorf("Ожидали длительность 30m, получили %s", DefaultEventDuration)
+	}
+}
+
+func TestLoadFreeSettings(t *testing.T) {
+	keepSettings(t)
+	path := writeConfig(t, `
+[free]
+work_start = "10:30"
+horizon = "48h"
+`)
+	t.Setenv("CALENDAR_FREE_WORK_END", "24:00")
+	if err := Load(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if FreeSearchHorizon != 48*time.Hour {
+		t.Errorf("Ожидали горизонт 48h, получили %s", FreeSearchHorizon)
+	}
+	if WorkDayStart != 10*time.Hour+30*time.Minute || WorkDayEnd != 24*time.Hour {
+		t.Errorf("Ожидали рабочие часы 10:30-24:00, получили %s-%s", formatClock(WorkDayStart), formatClock(WorkDayEnd))
+	}
+	if s, _ := Lookup("free.work_start"); s.Value() != "10:30" {
+		t.Errorf("Ожидали значение 10:30, получили %q", s.Value())
+	}
+}
+
+func TestSettingNames(t *testing.T) {
+	s, ok := Lookup("list.page_size")
+	if !ok {
+		t.Fatal("Ожидали найти настройку list.page_size")
+	}
+	if s.Flag() != "list-page-size" || s.Env() != "CALENDAR_LIST_PAGE_SIZE" {
+		t.Errorf("Ожидали имена флага и переменной, получили: %q, %q", s.Flag(), s.Env())
+	}
+}
@@ -0,0 +1,43 @@
+package datetime
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Day = 24 * time.Hour
+
+// ParseDuration понимает всё, что понимает time.ParseDuration, и дополнительно
+// ведущую компоненту в днях: "2d", "1d12h", "-1d".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	sign := time.Duration(1)
+	rest := s
+	if strings.HasPrefix(rest, "-") {
+		sign = -1
+		rest = rest[1:]
+	} else if strings.HasPrefix(rest, "+") {
+		rest = rest[1:]
+	}
+
+	i := strings.IndexByte(rest, 'd')
+	if i <= 0 {
+		return time.ParseDuration(s)
+	}
+	days, err := strconv.Atoi(rest[:i])
+	if err != nil {
+		return time.ParseDuration(s)
+	}
+
+	d := time.Duration(days) * Day
+	if rest[i+1:] != "" {
+		tail, err := time.ParseDuration(rest[i+1:])
+		if err != nil {
+			return 0, err
+		}
+		d += tail
+	}
+	return sign * d, nil
+}
@@ -5,13 +5,22 @@ import (
 	"time"
 )
 
-const LayoutFormat = "2006-01-02 15:04"
+const (
+	LayoutFormat     = "2006-01-02 15:04"
+	DateLayoutFormat = "2006-01-02"
+	TimeLayoutFormat = "15:04"
+)
 
 func ParseLocal(s string) (time.Time, error) {
 	s = strings.TrimSpace(s)
 	return time.ParseInLocation(LayoutFormat, s, time.Local)
 }
 
+func ParseLocalDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	return time.ParseInLocation(DateLayoutFormat, s, time.Local)
+}
+
 func NormalizeUTCSeconds(t time.Time) time.Time {
 	return t.UTC().Truncate(time.Second)
 }
@@ -19,3 +28,29 @@ func NormalizeUTCSeconds(t time.Time) time.Time {
 func FormatLocal(t time.Time) string {
 	return t.In(time.Local).Format(LayoutFormat)
 }
+
+func FormatLocalDate(t time.Time) string {
+	return t.In(time.Local).Format(DateLayoutFormat)
+}
+
+func FormatRange(start, end time.Time, allDay bool) string {
+	if allDay {
+		first := FormatLocalDate(start)
+		if end.IsZero() {
+			return first
+		}
+		last := FormatLocalDate(end.In(time.Local).AddDate(0, 0, -1))
+		if last <= first {
+			return first
+		}
+		return first + " - " + last
+	}
+
+	if end.IsZero() || !end.After(start) {
+		return FormatLocal(start)
+	}
+	if FormatLocalDate(start) == FormatLocalDate(end) {
+		return FormatLocal(start) + " - " + end.In(time.Local).Format(TimeLayoutFormat)
+	}
+	return FormatLocal(start) + " - " + FormatLocal(end)
+}
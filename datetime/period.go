@@ -0,0 +1,47 @@
+package datetime
+
+import (
+	"strings"
+	"time"
+)
+
+const MonthLayoutFormat = "2006-01"
+
+var (
+	WeekdayShortNames = [7]string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+	MonthNames        = [12]string{
+		"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+		"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь",
+	}
+)
+
+// WeekdayIndex возвращает номер дня недели в локальном времени, начиная с понедельника (0) до воскресенья (6).
+func WeekdayIndex(t time.Time) int {
+	return (int(t.In(time.Local).Weekday()) + 6) % 7
+}
+
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// StartOfWeek возвращает полночь понедельника недели, в которую попадает t.
+func StartOfWeek(t time.Time) time.Time {
+	return StartOfDay(t).AddDate(0, 0, -WeekdayIndex(t))
+}
+
+func StartOfMonth(t time.Time) time.Time {
+	y, m, _ := t.In(time.Local).Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+}
+
+func ParseLocalMonth(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	return time.ParseInLocation(MonthLayoutFormat, s, time.Local)
+}
+
+// FormatDayHeader форматирует дату для заголовка дня: "Пн 19.10.2026".
+func FormatDayHeader(t time.Time) string {
+	t = t.In(time.Local)
+	return WeekdayShortNames[WeekdayIndex(t)] + " " + t.Format("02.01.2006")
+}
@@ -0,0 +1,33 @@
+package datetime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartOfWeek(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2026-10-19 09:30", "2026-10-19"}, // понедельник
+		{"2026-10-21 00:00", "2026-10-19"},
+		{"2026-10-25 23:59", "2026-10-19"}, // воскресенье
+		{"2026-11-01 12:00", "2026-10-26"},
+	}
+	for _, tt := range tests {
+		in, err := ParseLocal(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := StartOfWeek(in)
+		if FormatLocalDate(got) != tt.want || got.Hour() != 0 {
+			t.Errorf("Ожидали начало недели %s для %s, получили: %v", tt.want, tt.in, got)
+		}
+	}
+
+	sunday := time.Date(2026, 10, 25, 12, 0, 0, 0, time.Local)
+	if WeekdayIndex(sunday) != 6 {
+		t.Errorf("Ожидали индекс 6 для воскресенья, получили: %d", WeekdayIndex(sunday))
+	}
+}
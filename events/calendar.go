@@ -0,0 +1,76 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultCalendar — календарь событий без явно указанного календаря. Он существует
+// всегда и в событиях не записывается.
+const DefaultCalendar = "default"
+
+var (
+	ErrInvalidCalendarName = errors.New("неверное имя календаря")
+	ErrInvalidColor        = errors.New("неверный цвет календаря")
+)
+
+// Colors — допустимые названия цветов календаря; кроме них цвет можно задать как #RRGGBB.
+var Colors = []string{"red", "green", "yellow", "blue", "magenta", "cyan", "white", "gray"}
+
+var (
+	calendarNameRe = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_-]{0,31}$`)
+	hexColorRe     = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+)
+
+// Calendar — именованный календарь, к которому относятся события. Priority
+// подставляется в события, добавленные в календарь без приоритета.
+type Calendar struct {
+	Name     string   `json:"name"`
+	Color    string   `json:"color,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+}
+
+// NewCalendar проверяет параметры и создаёт календарь; имя и цвет приводятся к
+// нижнему регистру.
+func NewCalendar(name, color string, priority Priority) (*Calendar, error) {
+	c := &Calendar{
+		Name:     NormalizeCalendarName(name),
+		Color:    strings.ToLower(strings.TrimSpace(color)),
+		Priority: priority,
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Calendar) Validate() error {
+	if !calendarNameRe.MatchString(c.Name) {
+		return fmt.Errorf("%w %q: допустимы буквы, цифры, - и _, не длиннее 32 символов", ErrInvalidCalendarName, c.Name)
+	}
+	if c.Color != "" && !slices.Contains(Colors, c.Color) && !hexColorRe.MatchString(c.Color) {
+		return fmt.Errorf("%w %q: допустимо %s или #RRGGBB", ErrInvalidColor, c.Color, strings.Join(Colors, ", "))
+	}
+	if c.Priority != "" {
+		if err := c.Priority.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NormalizeCalendarName приводит имя календаря к виду, в котором оно хранится.
+func NormalizeCalendarName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CalendarName возвращает календарь события; пустое поле означает DefaultCalendar.
+func (e *Event) CalendarName() string {
+	if e.Calendar == "" {
+		return DefaultCalendar
+	}
+	return e.Calendar
+}
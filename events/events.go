@@ -1,8 +1,10 @@
 package events
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,11 +14,22 @@ import (
 )
 
 type Event struct {
-	ID       string             `json:"id"`
-	Title    string             `json:"title"`
-	StartAt  time.Time          `json:"start_at"`
-	Priority Priority           `json:"priority"`
-	Reminder *reminder.Reminder `json:"reminder"`
+	ID        string               `json:"id"`
+	Title     string               `json:"title"`
+	StartAt   time.Time            `json:"start_at"`
+	EndAt     time.Time            `json:"end_at,omitzero"`
+	AllDay    bool                 `json:"all_day,omitempty"`
+	Priority  Priority             `json:"priority"`
+	Reminders []*reminder.Reminder `json:"reminders,omitempty"`
+
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	Exceptions []Exception `json:"exceptions,omitempty"`
+
+	// Calendar — имя календаря события; пустое для DefaultCalendar.
+	Calendar string `json:"calendar,omitempty"`
+
+	// DeletedAt — время перемещения события в корзину.
+	DeletedAt time.Time `json:"deleted_at,omitzero"`
 }
 
 var (
@@ -24,37 +37,86 @@ var (
 	ErrInvalidDate       = errors.New("неверный формат даты")
 	ErrEmptyReminderTime = errors.New("время напоминания не может быть пустым")
 	ErrZeroDuration      = errors.New("время должно быть больше нуля")
+	ErrInvalidEnd        = errors.New("время окончания должно быть позже начала")
+
+	ErrNoUpcomingOccurrence = errors.New("у события нет предстоящих вхождений")
 )
 
-func makeEvent(id string, title string, dateStr string, p Priority, reminder *reminder.Reminder) (Event, error) {
+func parseStart(dateStr string) (time.Time, bool, error) {
+	if t, err := datetime.ParseLocal(dateStr); err == nil {
+		return t, false, nil
+	}
+	if t, err := datetime.ParseLocalDate(dateStr); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("ошибка проверки даты/времени: %w", ErrInvalidDate)
+}
+
+func parseEnd(endStr string, start time.Time) (time.Time, error) {
+	if endStr == "" {
+		return time.Time{}, nil
+	}
+
+	var end time.Time
+	if d, err := datetime.ParseDuration(endStr); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("ошибка проверки окончания: %w", ErrZeroDuration)
+		}
+		end = start.Add(d)
+	} else if t, err := datetime.ParseLocal(endStr); err == nil {
+		end = t
+	} else if t, err := datetime.ParseLocalDate(endStr); err == nil {
+		end = t.AddDate(0, 0, 1)
+	} else {
+		return time.Time{}, fmt.Errorf("ошибка проверки окончания: %w", ErrInvalidDate)
+	}
+
+	if !end.After(start) {
+		return time.Time{}, fmt.Errorf("ошибка проверки окончания: %w", ErrInvalidEnd)
+	}
+	return end, nil
+}
+
+func makeEvent(id string, title string, dateStr string, endStr string, p Priority, reminders []*reminder.Reminder) (Event, error) {
 	title = strings.TrimSpace(title)
 	dateStr = strings.TrimSpace(dateStr)
+	endStr = strings.TrimSpace(endStr)
 
 	if !isValidTitle(title) {
 		return Event{}, fmt.Errorf("ошибка проверки заголовка: %w", ErrInvalidTitle)
 	}
 
-	t, err := datetime.ParseLocal(dateStr)
+	t, allDay, err := parseStart(dateStr)
+	if err != nil {
+		return Event{}, err
+	}
+
+	end, err := parseEnd(endStr, t)
 	if err != nil {
-		return Event{}, fmt.Errorf("ошибка проверки даты/времени: %w", ErrInvalidDate)
+		return Event{}, err
 	}
 
 	t = datetime.NormalizeUTCSeconds(t)
+	if !end.IsZero() {
+		end = datetime.NormalizeUTCSeconds(end)
+	}
 
 	if err := p.Validate(); err != nil {
 		return Event{}, err
 	}
 	return Event{
-		ID:       id,
-		Title:    title,
-		StartAt:  t,
-		Priority: p,
-		Reminder: reminder,
+		ID:        id,
+		Title:     title,
+		StartAt:   t,
+		EndAt:     end,
+		AllDay:    allDay,
+		Priority:  p,
+		Reminders: reminders,
 	}, nil
 }
 
-func NewEvent(title string, dateStr string, priority Priority) (*Event, error) {
-	event, err := makeEvent(generateUUID(), title, dateStr, priority, nil)
+func NewEvent(title string, dateStr string, endStr string, priority Priority) (*Event, error) {
+	event, err := makeEvent(generateUUID(), title, dateStr, endStr, priority, nil)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Ошибка создания события: %v", err))
 		return nil, err
@@ -63,42 +125,138 @@ func NewEvent(title string, dateStr string, priority Priority) (*Event, error) {
 	return &event, nil
 }
 
-func (e *Event) Update(title string, dateStr string, priority Priority) error {
-	updatedEvent, err := makeEvent(e.ID, title, dateStr, priority, e.Reminder)
+func (e *Event) Update(title string, dateStr string, endStr string, priority Priority) error {
+	updated, err := e.Updated(title, dateStr, endStr, priority)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Ошибка обновления события ID=%s: %v", e.ID, err))
 		return err
 	}
-	e.Title = updatedEvent.Title
-	e.StartAt = updatedEvent.StartAt
-	e.Priority = updatedEvent.Priority
+	if len(e.Exceptions) > 0 && len(updated.Exceptions) == 0 {
+		logger.Info(fmt.Sprintf("Исключения повторения сброшены для события ID=%s", e.ID))
+	}
+	e.Title = updated.Title
+	e.StartAt = updated.StartAt
+	e.EndAt = updated.EndAt
+	e.AllDay = updated.AllDay
+	e.Priority = updated.Priority
+	e.Exceptions = updated.Exceptions
+	e.rescheduleReminders()
 	logger.Info(fmt.Sprintf("Обновлено событие: ID=%s, NewTitle=%s", e.ID, e.Title))
 	return nil
 }
 
-func (e *Event) AddReminder(message string, at string, notify func(string)) error {
+// Updated возвращает копию события с новыми полями без напоминаний, не изменяя
+// исходное событие. Исключения повторения сбрасываются, если изменилось начало.
+func (e *Event) Updated(title string, dateStr string, endStr string, priority Priority) (*Event, error) {
+	fields, err := makeEvent(e.ID, title, dateStr, endStr, priority, nil)
+	if err != nil {
+		return nil, err
+	}
+	updated := e.Clone()
+	updated.Reminders = nil
+	if !e.StartAt.Equal(fields.StartAt) {
+		updated.Exceptions = nil
+	}
+	updated.Title = fields.Title
+	updated.StartAt = fields.StartAt
+	updated.EndAt = fields.EndAt
+	updated.AllDay = fields.AllDay
+	updated.Priority = fields.Priority
+	return updated, nil
+}
+
+func (e *Event) Validate() error {
+	if !isValidTitle(e.Title) {
+		return fmt.Errorf("ошибка проверки заголовка: %w", ErrInvalidTitle)
+	}
+	if e.StartAt.IsZero() {
+		return fmt.Errorf("ошибка проверки даты/времени: %w", ErrInvalidDate)
+	}
+	if !e.EndAt.IsZero() && !e.EndAt.After(e.StartAt) {
+		return fmt.Errorf("ошибка проверки окончания: %w", ErrInvalidEnd)
+	}
+	if err := e.Priority.Validate(); err != nil {
+		return err
+	}
+	if e.Recurrence != nil {
+		if err := e.Recurrence.Validate(); err != nil {
+			return err
+		}
+		for _, ex := range e.Exceptions {
+			if !e.isOccurrence(ex.RecurrenceID) {
+				return fmt.Errorf("%s: %w", datetime.FormatLocal(ex.RecurrenceID), ErrOccurrenceNotFound)
+			}
+		}
+	} else if len(e.Exceptions) > 0 {
+		return ErrNotRecurring
+	}
+	return nil
+}
+
+func (e *Event) Clone() *Event {
+	clone := *e
+	clone.Reminders = nil
+	for _, r := range e.Reminders {
+		clone.Reminders = append(clone.Reminders, r.Snapshot())
+	}
+	if e.Recurrence != nil {
+		rule := *e.Recurrence
+		rule.ByDay = append([]WeekdayNum(nil), e.Recurrence.ByDay...)
+		clone.Recurrence = &rule
+	}
+	clone.Exceptions = append([]Exception(nil), e.Exceptions...)
+	return &clone
+}
+
+func (e *Event) EndTime() time.Time {
+	if !e.EndAt.IsZero() {
+		return e.EndAt
+	}
+	if e.AllDay {
+		return e.StartAt.In(time.Local).AddDate(0, 0, 1).UTC()
+	}
+	return e.StartAt
+}
+
+func (e *Event) Duration() time.Duration {
+	return e.EndTime().Sub(e.StartAt)
+}
+
+func (e *Event) AddReminder(message string, at string, notify func(string)) (*reminder.Reminder, error) {
 	at = strings.TrimSpace(at)
 	if at == "" {
 		err := fmt.Errorf("ошибка проверки даты/времени: %w", ErrEmptyReminderTime)
 		logger.Error(fmt.Sprintf("Ошибка добавления напоминания для события ID=%s: %v", e.ID, err))
-		return err
+		return nil, err
 	}
 
 	var t time.Time
+	var offset time.Duration
 
-	if d, err := time.ParseDuration(at); err == nil {
-		if d <= 0 {
+	if d, err := datetime.ParseDuration(at); err == nil {
+		switch {
+		case d == 0:
 			err := fmt.Errorf("ошибка проверки даты/времени: %w", ErrZeroDuration)
 			logger.Error(fmt.Sprintf("Ошибка добавления напоминания для события ID=%s: %v", e.ID, err))
-			return err
+			return nil, err
+		case d < 0:
+			offset = -d
+			tt, ok := e.relativeReminderAt(offset, time.Now())
+			if !ok {
+				err := fmt.Errorf("ошибка проверки даты/времени: %w", ErrNoUpcomingOccurrence)
+				logger.Error(fmt.Sprintf("Ошибка добавления напоминания для события ID=%s: %v", e.ID, err))
+				return nil, err
+			}
+			t = tt
+		default:
+			t = time.Now().Add(d)
 		}
-		t = time.Now().Add(d)
 	} else {
 		tt, err2 := datetime.ParseLocal(at)
 		if err2 != nil {
 			err := fmt.Errorf("ошибка проверки даты/времени: %w", ErrInvalidDate)
 			logger.Error(fmt.Sprintf("Ошибка добавления напоминания для события ID=%s: %v", e.ID, err))
-			return err
+			return nil, err
 		}
 		t = tt
 	}
@@ -108,18 +266,121 @@ func (e *Event) AddReminder(message string, at string, notify func(string)) erro
 	r, err := reminder.NewReminder(message, t, notify)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Ошибка создания напоминания для события ID=%s: %v", e.ID, err))
-		return err
+		return nil, err
+	}
+	r.ID = e.nextReminderID()
+	r.Offset = offset
+	e.Reminders = append(e.Reminders, r)
+	r.Start()
+	logger.Info(fmt.Sprintf("Добавлено напоминание для события ID=%s: ReminderID=%s, Message=%s", e.ID, r.ID, message))
+	return r, nil
+}
+
+func (e *Event) RestoreReminder(message string, at time.Time, offset time.Duration) (*reminder.Reminder, error) {
+	if offset > 0 {
+		if t, ok := e.relativeReminderAt(offset, time.Now()); ok {
+			at = t
+		} else {
+			at = e.StartAt.Add(-offset)
+		}
+	}
+
+	r, err := reminder.Restore(message, at)
+	if err != nil {
+		return nil, err
+	}
+	r.ID = e.nextReminderID()
+	r.Offset = offset
+	e.Reminders = append(e.Reminders, r)
+	return r, nil
+}
+
+func (e *Event) nextReminderID() string {
+	next := 1
+	for _, r := range e.Reminders {
+		if n, err := strconv.Atoi(r.ID); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return strconv.Itoa(next)
+}
+
+func (e *Event) relativeReminderAt(offset time.Duration, now time.Time) (time.Time, bool) {
+	occ, ok := e.NextOccurrence(now.Add(offset))
+	if !ok {
+		if e.IsRecurring() {
+			return time.Time{}, false
+		}
+		return e.StartAt.Add(-offset), true
+	}
+	return occ.StartAt.Add(-offset), true
+}
+
+func (e *Event) FindReminder(reminderID string) *reminder.Reminder {
+	for _, r := range e.Reminders {
+		if r.ID == reminderID {
+			return r
+		}
 	}
-	e.Reminder = r
-	e.Reminder.Start()
-	logger.Info(fmt.Sprintf("Добавлено напоминание для события ID=%s: Message=%s", e.ID, message))
 	return nil
 }
 
-func (e *Event) RemoveReminder() {
-	if e.Reminder != nil {
-		e.Reminder.Stop()
-		logger.Info(fmt.Sprintf("Напоминание остановлено для события ID=%s", e.ID))
-		e.Reminder = nil
+func (e *Event) RemoveReminder(reminderID string) bool {
+	for i, r := range e.Reminders {
+		if r.ID != reminderID {
+			continue
+		}
+		r.Stop()
+		e.Reminders = append(e.Reminders[:i], e.Reminders[i+1:]...)
+		logger.Info(fmt.Sprintf("Напоминание остановлено для события ID=%s: ReminderID=%s", e.ID, reminderID))
+		return true
+	}
+	return false
+}
+
+func (e *Event) RemoveReminders() {
+	for _, r := range e.Reminders {
+		r.Stop()
+	}
+	if len(e.Reminders) > 0 {
+		logger.Info(fmt.Sprintf("Напоминания остановлены для события ID=%s", e.ID))
+	}
+	e.Reminders = nil
+}
+
+func (e *Event) rescheduleReminders() {
+	now := time.Now()
+	for _, r := range e.Reminders {
+		if r.Offset <= 0 {
+			continue
+		}
+		at, ok := e.relativeReminderAt(r.Offset, now)
+		if !ok {
+			r.Stop()
+			continue
+		}
+		r.Reschedule(at)
+		logger.Info(fmt.Sprintf("Напоминание перенесено для события ID=%s: ReminderID=%s, At=%s", e.ID, r.ID, datetime.FormatLocal(at)))
+	}
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type eventAlias Event
+	aux := struct {
+		*eventAlias
+		Reminder *reminder.Reminder `json:"reminder"`
+	}{eventAlias: (*eventAlias)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.Reminder != nil {
+		e.Reminders = append(e.Reminders, aux.Reminder)
 	}
+	for _, r := range e.Reminders {
+		if r.ID == "" {
+			r.ID = e.nextReminderID()
+		}
+	}
+	return nil
 }
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMakeEventEnd(t *testing.T) {
+	e, err := makeEvent("id", "Встреча", "2030-08-12 07:00", "2h", PriorityHigh, nil)
+	if err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+	if e.Duration() != 2*time.Hour {
+		t.Errorf("Ожидали длительность 2h, получили %v", e.Duration())
+	}
+
+	_, err = makeEvent("id", "Встреча", "2030-08-12 07:00", "2030-08-12 06:00", PriorityHigh, nil)
+	if !errors.Is(err, ErrInvalidEnd) {
+		t.Errorf("Ожидали ErrInvalidEnd, получили: %v", err)
+	}
+
+	e, err = makeEvent("id", "Праздник", "2030-08-12", "", PriorityLow, nil)
+	if err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+	if !e.AllDay {
+		t.Error("Ожидали событие на весь день для даты без времени")
+	}
+	if e.Duration() != 24*time.Hour {
+		t.Errorf("Ожидали длительность 24h, получили %v", e.Duration())
+	}
+}
+
+func TestUnmarshalLegacyReminder(t *testing.T) {
+	data := []byte(`{"id":"id","title":"Встреча","start_at":"2030-08-12T07:00:00Z","priority":"low",
+		"reminder":{"message":"Сообщение","at":"2030-08-12T06:00:00Z","sent":false}}`)
+
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+	if len(e.Reminders) != 1 {
+		t.Fatalf("Ожидали одно напоминание, получили %d", len(e.Reminders))
+	}
+	if e.Reminders[0].ID != "1" {
+		t.Errorf("Ожидали ID напоминания \"1\", получили %q", e.Reminders[0].ID)
+	}
+}
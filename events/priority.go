@@ -20,3 +20,17 @@ func (p Priority) Validate() error {
 		return ErrInvalidPriority
 	}
 }
+
+// Rank возвращает порядковый вес приоритета для сортировки: чем выше приоритет, тем больше вес.
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityHigh:
+		return 3
+	case PriorityMedium:
+		return 2
+	case PriorityLow:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,686 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+const maxRecurrencePeriods = 100000
+
+var (
+	ErrInvalidRecurrence     = errors.New("неверное правило повторения")
+	ErrUnsupportedRecurrence = errors.New("правило повторения не поддерживается")
+	ErrNotRecurring          = errors.New("событие не повторяется")
+	ErrOccurrenceNotFound    = errors.New("вхождение повторяющегося события не найдено")
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+func (w WeekdayNum) String() string {
+	code := strings.ToUpper(w.Day.String()[:2])
+	if w.N == 0 {
+		return code
+	}
+	return strconv.Itoa(w.N) + code
+}
+
+func (w WeekdayNum) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+func (w *WeekdayNum) UnmarshalText(b []byte) error {
+	parsed, err := parseWeekdayNum(string(b))
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("BYDAY=%q: %w", s, ErrInvalidRecurrence)
+	}
+	day, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("BYDAY=%q: %w", s, ErrInvalidRecurrence)
+	}
+	n := 0
+	if prefix := s[:len(s)-2]; prefix != "" {
+		v, err := strconv.Atoi(prefix)
+		if err != nil || v == 0 || v < -5 || v > 5 {
+			return WeekdayNum{}, fmt.Errorf("BYDAY=%q: %w", s, ErrInvalidRecurrence)
+		}
+		n = v
+	}
+	return WeekdayNum{N: n, Day: day}, nil
+}
+
+type Recurrence struct {
+	Freq     Frequency    `json:"freq"`
+	Interval int          `json:"interval,omitempty"`
+	ByDay    []WeekdayNum `json:"by_day,omitempty"`
+	Count    int          `json:"count,omitempty"`
+	Until    time.Time    `json:"until,omitzero"`
+}
+
+type Exception struct {
+	RecurrenceID time.Time `json:"recurrence_id"`
+	Skip         bool      `json:"skip,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	StartAt      time.Time `json:"start_at,omitzero"`
+}
+
+type Occurrence struct {
+	Event        *Event
+	Title        string
+	StartAt      time.Time
+	EndAt        time.Time
+	RecurrenceID time.Time
+}
+
+func (o Occurrence) EndTime() time.Time {
+	if o.EndAt.IsZero() {
+		return o.StartAt
+	}
+	return o.EndAt
+}
+
+// ParseRecurrence разбирает правило в формате RRULE (RFC 5545),
+// например "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+func ParseRecurrence(s string) (*Recurrence, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "RRULE:"), "rrule:")
+	if s == "" {
+		return nil, fmt.Errorf("пустое правило: %w", ErrInvalidRecurrence)
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: %w", part, ErrInvalidRecurrence)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			v, err := strconv.Atoi(value)
+			if err != nil || v < 1 {
+				return nil, fmt.Errorf("INTERVAL=%q: %w", value, ErrInvalidRecurrence)
+			}
+			r.Interval = v
+		case "COUNT":
+			v, err := strconv.Atoi(value)
+			if err != nil || v < 1 {
+				return nil, fmt.Errorf("COUNT=%q: %w", value, ErrInvalidRecurrence)
+			}
+			r.Count = v
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, fmt.Errorf("UNTIL=%q: %w", value, ErrInvalidRecurrence)
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				w, err := parseWeekdayNum(d)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, w)
+			}
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				return nil, fmt.Errorf("WKST=%q: %w", value, ErrUnsupportedRecurrence)
+			}
+		default:
+			return nil, fmt.Errorf("%s: %w", key, ErrUnsupportedRecurrence)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", s, time.Local); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.ParseInLocation("20060102", s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, 1).Add(-time.Second).UTC(), nil
+}
+
+func (r *Recurrence) Validate() error {
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	case "":
+		return fmt.Errorf("не указан FREQ: %w", ErrInvalidRecurrence)
+	default:
+		return fmt.Errorf("FREQ=%s: %w", r.Freq, ErrUnsupportedRecurrence)
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("INTERVAL=%d: %w", r.Interval, ErrInvalidRecurrence)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("COUNT и UNTIL одновременно: %w", ErrInvalidRecurrence)
+	}
+	for _, w := range r.ByDay {
+		if w.N != 0 && r.Freq != FreqMonthly {
+			return fmt.Errorf("BYDAY=%s при FREQ=%s: %w", w, r.Freq, ErrUnsupportedRecurrence)
+		}
+	}
+	if len(r.ByDay) > 0 && r.Freq == FreqYearly {
+		return fmt.Errorf("BYDAY при FREQ=%s: %w", r.Freq, ErrUnsupportedRecurrence)
+	}
+	return nil
+}
+
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, w := range r.ByDay {
+			days[i] = w.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (r *Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+func (r *Recurrence) hasDay(d time.Weekday) bool {
+	for _, w := range r.ByDay {
+		if w.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// period возвращает отсортированные кандидаты k-го периода правила в местном времени.
+func (r *Recurrence) period(start time.Time, k int) []time.Time {
+	step := k * r.interval()
+	hour, minute, sec := start.Clock()
+
+	switch r.Freq {
+	case FreqDaily:
+		t := start.AddDate(0, 0, step)
+		if len(r.ByDay) > 0 && !r.hasDay(t.Weekday()) {
+			return nil
+		}
+		return []time.Time{t}
+
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*step)}
+		}
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := start.AddDate(0, 0, 7*step-offset)
+		var out []time.Time
+		for i := 0; i < 7; i++ {
+			t := monday.AddDate(0, 0, i)
+			if r.hasDay(t.Weekday()) {
+				out = append(out, t)
+			}
+		}
+		return out
+
+	case FreqMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(step), 1, hour, minute, sec, 0, start.Location())
+		if len(r.ByDay) == 0 {
+			t := time.Date(first.Year(), first.Month(), start.Day(), hour, minute, sec, 0, start.Location())
+			if t.Month() != first.Month() {
+				return nil
+			}
+			return []time.Time{t}
+		}
+		var out []time.Time
+		for _, w := range r.ByDay {
+			out = append(out, monthWeekdays(first, w)...)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+		return dedupTimes(out)
+
+	case FreqYearly:
+		t := time.Date(start.Year()+step, start.Month(), start.Day(), hour, minute, sec, 0, start.Location())
+		if t.Month() != start.Month() {
+			return nil
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
+
+func monthWeekdays(first time.Time, w WeekdayNum) []time.Time {
+	var all []time.Time
+	for t := first; t.Month() == first.Month(); t = t.AddDate(0, 0, 1) {
+		if t.Weekday() == w.Day {
+			all = append(all, t)
+		}
+	}
+	switch {
+	case w.N == 0:
+		return all
+	case w.N > 0 && w.N <= len(all):
+		return all[w.N-1 : w.N]
+	case w.N < 0 && -w.N <= len(all):
+		i := len(all) + w.N
+		return all[i : i+1]
+	}
+	return nil
+}
+
+func dedupTimes(ts []time.Time) []time.Time {
+	out := ts[:0]
+	for i, t := range ts {
+		if i > 0 && t.Equal(ts[i-1]) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// firstPeriod возвращает номер периода, с которого можно начинать перебор вхождений
+// не раньше from. Правила с COUNT перебираются с начала, чтобы счёт вхождений был верным.
+func (r *Recurrence) firstPeriod(start, from time.Time) int {
+	if r.Count > 0 || !from.After(start) {
+		return 0
+	}
+	from = from.In(start.Location())
+	var periods int
+	switch r.Freq {
+	case FreqDaily:
+		periods = daysBetween(start, from)
+	case FreqWeekly:
+		periods = (daysBetween(start, from) + datetime.WeekdayIndex(start)) / 7
+	case FreqMonthly:
+		periods = (from.Year()-start.Year())*12 + int(from.Month()-start.Month())
+	case FreqYearly:
+		periods = from.Year() - start.Year()
+	}
+	// Предыдущий период тоже перебирается: вхождение могло начаться до from.
+	return max(periods/r.interval()-1, 0)
+}
+
+// daysBetween возвращает число календарных дней от from до to без учёта перехода на летнее время.
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// expand перебирает исходные даты вхождений (без учёта исключений) начиная с периода,
+// содержащего from, пока yield возвращает true.
+func (r *Recurrence) expand(dtstart, from time.Time, yield func(time.Time) bool) {
+	start := dtstart.In(time.Local)
+	n := 0
+	first := r.firstPeriod(start, from)
+	for k := first; k < first+maxRecurrencePeriods; k++ {
+		for _, t := range r.period(start, k) {
+			if t.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return
+			}
+			n++
+			if !yield(datetime.NormalizeUTCSeconds(t)) {
+				return
+			}
+			if r.Count > 0 && n >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+func (e *Event) IsRecurring() bool {
+	return e.Recurrence != nil
+}
+
+func (e *Event) exception(recurrenceID time.Time) (Exception, bool) {
+	for _, ex := range e.Exceptions {
+		if ex.RecurrenceID.Equal(recurrenceID) {
+			return ex, true
+		}
+	}
+	return Exception{}, false
+}
+
+func (e *Event) occurrenceAt(recurrenceID time.Time, ex *Exception) Occurrence {
+	duration := time.Duration(0)
+	if !e.EndAt.IsZero() || e.AllDay {
+		duration = e.Duration()
+	}
+	o := Occurrence{
+		Event:        e,
+		Title:        e.Title,
+		StartAt:      recurrenceID,
+		RecurrenceID: recurrenceID,
+	}
+	if ex != nil {
+		if ex.Title != "" {
+			o.Title = ex.Title
+		}
+		if !ex.StartAt.IsZero() {
+			o.StartAt = ex.StartAt
+		}
+	}
+	if duration > 0 {
+		o.EndAt = o.StartAt.Add(duration)
+	}
+	return o
+}
+
+func overlaps(o Occurrence, from, to time.Time) bool {
+	if !o.StartAt.Before(to) {
+		return false
+	}
+	end := o.EndTime()
+	return end.After(from) || !o.StartAt.Before(from)
+}
+
+func (e *Event) single() Occurrence {
+	o := Occurrence{Event: e, Title: e.Title, StartAt: e.StartAt, RecurrenceID: e.StartAt}
+	if !e.EndAt.IsZero() || e.AllDay {
+		o.EndAt = e.EndTime()
+	}
+	return o
+}
+
+// Occurrences возвращает вхождения события, пересекающиеся с интервалом [from, to).
+func (e *Event) Occurrences(from, to time.Time) []Occurrence {
+	if !e.IsRecurring() {
+		if o := e.single(); overlaps(o, from, to) {
+			return []Occurrence{o}
+		}
+		return nil
+	}
+
+	var out []Occurrence
+	lookback := from.Add(-e.Duration())
+	e.Recurrence.expand(e.StartAt, lookback, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if t.Before(lookback) {
+			return true
+		}
+		if _, ok := e.exception(t); ok {
+			return true
+		}
+		if o := e.occurrenceAt(t, nil); overlaps(o, from, to) {
+			out = append(out, o)
+		}
+		return true
+	})
+
+	for i := range e.Exceptions {
+		ex := &e.Exceptions[i]
+		if ex.Skip {
+			continue
+		}
+		if o := e.occurrenceAt(ex.RecurrenceID, ex); overlaps(o, from, to) {
+			out = append(out, o)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].StartAt.Before(out[j].StartAt) })
+	return out
+}
+
+// ExceptionOccurrences возвращает вхождения, изменённые исключениями, в порядке
+// исключений; пропущенные вхождения не включаются.
+func (e *Event) ExceptionOccurrences() []Occurrence {
+	var out []Occurrence
+	for i := range e.Exceptions {
+		ex := &e.Exceptions[i]
+		if ex.Skip {
+			continue
+		}
+		out = append(out, e.occurrenceAt(ex.RecurrenceID, ex))
+	}
+	return out
+}
+
+// NextOccurrence возвращает первое вхождение, начинающееся не раньше after.
+func (e *Event) NextOccurrence(after time.Time) (Occurrence, bool) {
+	if !e.IsRecurring() {
+		if e.StartAt.Before(after) {
+			return Occurrence{}, false
+		}
+		return e.single(), true
+	}
+
+	var next Occurrence
+	found := false
+	e.Recurrence.expand(e.StartAt, after, func(t time.Time) bool {
+		if t.Before(after) {
+			return true
+		}
+		if _, ok := e.exception(t); ok {
+			return true
+		}
+		next = e.occurrenceAt(t, nil)
+		found = true
+		return false
+	})
+
+	for i := range e.Exceptions {
+		ex := &e.Exceptions[i]
+		if ex.Skip {
+			continue
+		}
+		o := e.occurrenceAt(ex.RecurrenceID, ex)
+		if o.StartAt.Before(after) {
+			continue
+		}
+		if !found || o.StartAt.Before(next.StartAt) {
+			next = o
+			found = true
+		}
+	}
+	return next, found
+}
+
+func (e *Event) isOccurrence(recurrenceID time.Time) bool {
+	found := false
+	e.Recurrence.expand(e.StartAt, recurrenceID, func(t time.Time) bool {
+		if t.Equal(recurrenceID) {
+			found = true
+		}
+		return t.Before(recurrenceID)
+	})
+	return found
+}
+
+// NextReminderAt вычисляет время следующего срабатывания напоминания повторяющегося
+// события, сохраняя смещение относительно начала вхождения.
+func (e *Event) NextReminderAt(prev time.Time) (time.Time, bool) {
+	if !e.IsRecurring() {
+		return time.Time{}, false
+	}
+	current, ok := e.NextOccurrence(prev)
+	if !ok {
+		return time.Time{}, false
+	}
+	next, ok := e.NextOccurrence(current.StartAt.Add(time.Second))
+	if !ok {
+		return time.Time{}, false
+	}
+	return next.StartAt.Add(prev.Sub(current.StartAt)), true
+}
+
+func (e *Event) SetRecurrence(rule string) error {
+	r, err := ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+	e.Recurrence = r
+	e.Exceptions = nil
+	return nil
+}
+
+func (e *Event) RemoveRecurrence() error {
+	if !e.IsRecurring() {
+		return ErrNotRecurring
+	}
+	e.Recurrence = nil
+	e.Exceptions = nil
+	return nil
+}
+
+func (e *Event) setException(ex Exception) error {
+	if !e.IsRecurring() {
+		return ErrNotRecurring
+	}
+	if !e.isOccurrence(ex.RecurrenceID) {
+		return ErrOccurrenceNotFound
+	}
+	if ex.Title != "" && !isValidTitle(ex.Title) {
+		return fmt.Errorf("ошибка проверки заголовка: %w", ErrInvalidTitle)
+	}
+	for i := range e.Exceptions {
+		if e.Exceptions[i].RecurrenceID.Equal(ex.RecurrenceID) {
+			e.Exceptions[i] = ex
+			return nil
+		}
+	}
+	e.Exceptions = append(e.Exceptions, ex)
+	return nil
+}
+
+// parseOccurrenceTime разбирает дату и время вхождения; для события на весь день
+// достаточно даты.
+func (e *Event) parseOccurrenceTime(s string) (time.Time, error) {
+	t, err := datetime.ParseLocal(s)
+	if err != nil && e.AllDay {
+		t, err = datetime.ParseLocalDate(s)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return datetime.NormalizeUTCSeconds(t), nil
+}
+
+func (e *Event) parseRecurrenceID(atStr string) (time.Time, error) {
+	t, err := e.parseOccurrenceTime(atStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ошибка проверки даты/времени вхождения: %w", ErrInvalidDate)
+	}
+	return t, nil
+}
+
+func (e *Event) SkipOccurrence(atStr string) error {
+	id, err := e.parseRecurrenceID(atStr)
+	if err != nil {
+		return err
+	}
+	if err := e.setException(Exception{RecurrenceID: id, Skip: true}); err != nil {
+		logger.Error(fmt.Sprintf("Ошибка пропуска вхождения события ID=%s: %v", e.ID, err))
+		return err
+	}
+	logger.Info(fmt.Sprintf("Пропущено вхождение события ID=%s: %s", e.ID, datetime.FormatLocal(id)))
+	return nil
+}
+
+func (e *Event) EditOccurrence(atStr string, title string, dateStr string) error {
+	id, err := e.parseRecurrenceID(atStr)
+	if err != nil {
+		return err
+	}
+
+	ex := Exception{RecurrenceID: id}
+	if current, ok := e.exception(id); ok && !current.Skip {
+		ex = current
+	}
+
+	title = strings.TrimSpace(title)
+	if title != "_" {
+		if !isValidTitle(title) {
+			return fmt.Errorf("ошибка проверки заголовка: %w", ErrInvalidTitle)
+		}
+		ex.Title = title
+	}
+
+	dateStr = strings.TrimSpace(dateStr)
+	if dateStr != "_" {
+		t, err := e.parseOccurrenceTime(dateStr)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки даты/времени: %w", ErrInvalidDate)
+		}
+		ex.StartAt = t
+	}
+
+	if err := e.setException(ex); err != nil {
+		logger.Error(fmt.Sprintf("Ошибка изменения вхождения события ID=%s: %v", e.ID, err))
+		return err
+	}
+	logger.Info(fmt.Sprintf("Изменено вхождение события ID=%s: %s", e.ID, datetime.FormatLocal(id)))
+	return nil
+}
+
+func (e *Event) AddException(ex Exception) error {
+	return e.setException(ex)
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+)
+
+func mustLocal(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := datetime.ParseLocal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return datetime.NormalizeUTCSeconds(v)
+}
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10")
+	if err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+	if r.String() != "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10" {
+		t.Errorf("Неожиданное представление правила: %s", r)
+	}
+
+	if _, err := ParseRecurrence("FREQ=HOURLY"); !errors.Is(err, ErrUnsupportedRecurrence) {
+		t.Errorf("Ожидали ErrUnsupportedRecurrence, получили: %v", err)
+	}
+	if _, err := ParseRecurrence("FREQ=DAILY;COUNT=0"); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("Ожидали ErrInvalidRecurrence, получили: %v", err)
+	}
+}
+
+func TestOccurrencesWeekly(t *testing.T) {
+	e := &Event{Title: "Планерка", StartAt: mustLocal(t, "2030-01-07 10:00")}
+	if err := e.SetRecurrence("FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := e.Occurrences(mustLocal(t, "2030-01-01 00:00"), mustLocal(t, "2031-01-01 00:00"))
+	want := []string{"2030-01-07 10:00", "2030-01-09 10:00", "2030-01-14 10:00", "2030-01-16 10:00", "2030-01-21 10:00"}
+	if len(got) != len(want) {
+		t.Fatalf("Ожидали %d вхождений, получили %d", len(want), len(got))
+	}
+	for i, o := range got {
+		if datetime.FormatLocal(o.StartAt) != want[i] {
+			t.Errorf("Вхождение %d: ожидали %s, получили %s", i, want[i], datetime.FormatLocal(o.StartAt))
+		}
+	}
+}
+
+func TestOccurrencesMonthlyLastFriday(t *testing.T) {
+	e := &Event{Title: "Зарплата", StartAt: mustLocal(t, "2030-01-01 12:00")}
+	if err := e.SetRecurrence("FREQ=MONTHLY;BYDAY=-1FR"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := e.Occurrences(mustLocal(t, "2030-01-01 00:00"), mustLocal(t, "2030-04-01 00:00"))
+	want := []string{"2030-01-25 12:00", "2030-02-22 12:00", "2030-03-29 12:00"}
+	if len(got) != len(want) {
+		t.Fatalf("Ожидали %d вхождений, получили %d", len(want), len(got))
+	}
+	for i, o := range got {
+		if datetime.FormatLocal(o.StartAt) != want[i] {
+			t.Errorf("Вхождение %d: ожидали %s, получили %s", i, want[i], datetime.FormatLocal(o.StartAt))
+		}
+	}
+}
+
+func TestOccurrenceExceptions(t *testing.T) {
+	e := &Event{Title: "Пробежка", StartAt: mustLocal(t, "2030-01-01 07:00")}
+	if err := e.SetRecurrence("FREQ=DAILY"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.setException(Exception{RecurrenceID: mustLocal(t, "2030-01-02 07:00"), Skip: true}); err != nil {
+		t.Fatal(err)
+	}
+	moved := Exception{RecurrenceID: mustLocal(t, "2030-01-03 07:00"), StartAt: mustLocal(t, "2030-01-03 09:00")}
+	if err := e.setException(moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.setException(Exception{RecurrenceID: mustLocal(t, "2030-01-03 08:00"), Skip: true}); !errors.Is(err, ErrOccurrenceNotFound) {
+		t.Errorf("Ожидали ErrOccurrenceNotFound, получили: %v", err)
+	}
+
+	got := e.Occurrences(mustLocal(t, "2030-01-01 00:00"), mustLocal(t, "2030-01-04 00:00"))
+	want := []string{"2030-01-01 07:00", "2030-01-03 09:00"}
+	if len(got) != len(want) {
+		t.Fatalf("Ожидали %d вхождений, получили %d", len(want), len(got))
+	}
+	for i, o := range got {
+		if datetime.FormatLocal(o.StartAt) != want[i] {
+			t.Errorf("Вхождение %d: ожидали %s, получили %s", i, want[i], datetime.FormatLocal(o.StartAt))
+		}
+	}
+
+	next, ok := e.NextOccurrence(mustLocal(t, "2030-01-01 08:00"))
+	if !ok || datetime.FormatLocal(next.StartAt) != "2030-01-03 09:00" {
+		t.Errorf("Ожидали следующее вхождение 2030-01-03 09:00, получили %s", datetime.FormatLocal(next.StartAt))
+	}
+}
+
+func TestNextReminderAt(t *testing.T) {
+	e := &Event{Title: "Пробежка", StartAt: mustLocal(t, "2030-01-01 07:00")}
+	if err := e.SetRecurrence("FREQ=DAILY;COUNT=2"); err != nil {
+		t.Fatal(err)
+	}
+
+	at, ok := e.NextReminderAt(mustLocal(t, "2030-01-01 06:45"))
+	if !ok || datetime.FormatLocal(at) != "2030-01-02 06:45" {
+		t.Errorf("Ожидали следующее напоминание 2030-01-02 06:45, получили %s", datetime.FormatLocal(at))
+	}
+
+	if _, ok := e.NextReminderAt(at); ok {
+		t.Error("Ожидали, что после последнего вхождения напоминаний больше нет")
+	}
+}
+
+func TestOccurrencesFarFromStart(t *testing.T) {
+	rules := []string{
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=DAILY;BYDAY=SA,SU",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		"FREQ=MONTHLY;INTERVAL=5",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=YEARLY;INTERVAL=4",
+	}
+	from, to := mustLocal(t, "2150-03-01 00:00"), mustLocal(t, "2150-03-01 00:00").AddDate(4, 0, 0)
+	for _, rule := range rules {
+		e := &Event{Title: "Далеко", StartAt: mustLocal(t, "2000-01-31 10:00"), EndAt: mustLocal(t, "2000-01-31 11:00")}
+		if err := e.SetRecurrence(rule); err != nil {
+			t.Fatal(err)
+		}
+		var want []string
+		e.Recurrence.expand(e.StartAt, time.Time{}, func(s time.Time) bool {
+			if !s.Before(to) {
+				return false
+			}
+			if !s.Before(from) {
+				want = append(want, datetime.FormatLocal(s))
+			}
+			return true
+		})
+		got := e.Occurrences(from, to)
+		if len(want) == 0 || len(got) != len(want) {
+			t.Fatalf("%s: ожидали %d вхождений, получили %d", rule, len(want), len(got))
+		}
+		for i, o := range got {
+			if datetime.FormatLocal(o.StartAt) != want[i] {
+				t.Errorf("%s: вхождение %d: ожидали %s, получили %s", rule, i, want[i], datetime.FormatLocal(o.StartAt))
+			}
+		}
+	}
+
+	// Ежедневное событие через сотни лет после начала всё ещё находится.
+	e := &Event{Title: "Ежедневно", StartAt: mustLocal(t, "2000-01-01 09:00")}
+	if err := e.SetRecurrence("FREQ=DAILY"); err != nil {
+		t.Fatal(err)
+	}
+	next, ok := e.NextOccurrence(mustLocal(t, "2400-06-15 10:00"))
+	if !ok || datetime.FormatLocal(next.StartAt) != "2400-06-16 09:00" {
+		t.Errorf("Ожидали вхождение 2400-06-16 09:00, получили %s", datetime.FormatLocal(next.StartAt))
+	}
+}
+
+func TestEditOccurrenceAllDay(t *testing.T) {
+	e := &Event{Title: "Дежурство", StartAt: mustLocal(t, "2030-01-01 00:00"), EndAt: mustLocal(t, "2030-01-02 00:00"), AllDay: true}
+	if err := e.SetRecurrence("FREQ=WEEKLY"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.EditOccurrence("2030-01-08", "_", "2030-01-09"); err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+	if err := e.SkipOccurrence("2030-01-15"); err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+
+	got := e.Occurrences(mustLocal(t, "2030-01-02 00:00"), mustLocal(t, "2030-01-23 00:00"))
+	want := []string{"2030-01-09", "2030-01-22"}
+	if len(got) != len(want) {
+		t.Fatalf("Ожидали %d вхождений, получили %d", len(want), len(got))
+	}
+	for i, o := range got {
+		if datetime.FormatLocalDate(o.StartAt) != want[i] {
+			t.Errorf("Вхождение %d: ожидали %s, получили %s", i, want[i], datetime.FormatLocalDate(o.StartAt))
+		}
+	}
+
+	timed := &Event{Title: "Планерка", StartAt: mustLocal(t, "2030-01-01 10:00")}
+	if err := timed.SetRecurrence("FREQ=DAILY"); err != nil {
+		t.Fatal(err)
+	}
+	if err := timed.SkipOccurrence("2030-01-02"); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("Ожидали ErrInvalidDate, получили: %v", err)
+	}
+}
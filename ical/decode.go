@@ -0,0 +1,459 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+type component struct {
+	name     string
+	props    []property
+	children []*component
+}
+
+func (c *component) prop(name string) (property, bool) {
+	for _, p := range c.props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			inQuote = !inQuote
+		case s[i] == sep && !inQuote:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseLine(s string) (property, error) {
+	inQuote := false
+	colon := -1
+	for i := 0; i < len(s) && colon < 0; i++ {
+		switch {
+		case s[i] == '"':
+			inQuote = !inQuote
+		case s[i] == ':' && !inQuote:
+			colon = i
+		}
+	}
+	if colon <= 0 {
+		return property{}, fmt.Errorf("строка %q: %w", s, ErrInvalidFormat)
+	}
+
+	head := splitOutsideQuotes(s[:colon], ';')
+	p := property{
+		name:   strings.ToUpper(head[0]),
+		params: make(map[string]string),
+		value:  s[colon+1:],
+	}
+	for _, param := range head[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return p, nil
+}
+
+func parse(r io.Reader) (*component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &component{}
+	stack := []*component{root}
+	for _, line := range lines {
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+
+		switch p.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(p.value)}
+			top.children = append(top.children, c)
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 1 || top.name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("END:%s без BEGIN: %w", p.value, ErrInvalidFormat)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			top.props = append(top.props, p)
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("не закрыт компонент %s: %w", stack[len(stack)-1].name, ErrInvalidFormat)
+	}
+	return root, nil
+}
+
+type decoder struct {
+	result    *Result
+	masters   map[string]*events.Event
+	overrides []*component
+}
+
+func (d *decoder) warnf(format string, args ...any) {
+	d.result.Warnings = append(d.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+func Import(r io.Reader) (*Result, error) {
+	root, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decoder{
+		result:  &Result{},
+		masters: make(map[string]*events.Event),
+	}
+
+	found := false
+	for _, cal := range root.children {
+		if cal.name != "VCALENDAR" {
+			d.warnf("компонент %s вне VCALENDAR не поддерживается", cal.name)
+			continue
+		}
+		found = true
+		d.calendar(cal)
+	}
+	if !found {
+		return nil, ErrNoCalendar
+	}
+
+	for _, c := range d.overrides {
+		d.override(c)
+	}
+	return d.result, nil
+}
+
+func (d *decoder) calendar(cal *component) {
+	for _, p := range cal.props {
+		switch p.name {
+		case "VERSION", "PRODID", "CALSCALE", "METHOD":
+		default:
+			d.warnf("VCALENDAR: свойство %s не поддерживается", p.name)
+		}
+	}
+
+	for _, c := range cal.children {
+		switch c.name {
+		case "VEVENT":
+			if _, ok := c.prop("RECURRENCE-ID"); ok {
+				d.overrides = append(d.overrides, c)
+				continue
+			}
+			if e := d.event(c); e != nil {
+				if _, dup := d.masters[e.ID]; dup {
+					d.warnf("UID=%s: повторяющийся UID, событие пропущено", e.ID)
+					continue
+				}
+				d.masters[e.ID] = e
+				d.result.Events = append(d.result.Events, e)
+			}
+		case "VTIMEZONE":
+			// Часовые пояса разрешаются по TZID через базу IANA.
+		default:
+			d.warnf("компонент %s не поддерживается", c.name)
+		}
+	}
+}
+
+func (d *decoder) parseTime(uid string, p property) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+	if strings.ToUpper(p.params["VALUE"]) == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			d.warnf("UID=%s: неизвестный часовой пояс %s, использовано местное время", uid, tzid)
+		} else {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localLayout, value, loc)
+	return t, false, err
+}
+
+func parsePriority(value string) events.Priority {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil || n == 0 || n == 5:
+		return events.PriorityMedium
+	case n < 5:
+		return events.PriorityHigh
+	default:
+		return events.PriorityLow
+	}
+}
+
+func (d *decoder) uid(c *component) string {
+	if p, ok := c.prop("UID"); ok && strings.TrimSpace(p.value) != "" {
+		return unescapeText(strings.TrimSpace(p.value))
+	}
+	return ""
+}
+
+func (d *decoder) event(c *component) *events.Event {
+	uid := d.uid(c)
+	if uid == "" {
+		uid = uuid.New().String()
+		d.warnf("VEVENT без UID: присвоен UID=%s", uid)
+	}
+
+	e := &events.Event{ID: uid, Priority: events.PriorityMedium}
+	var end time.Time
+	var duration time.Duration
+	var exdates []property
+
+	for _, p := range c.props {
+		switch p.name {
+		case "UID", "DTSTAMP", "CREATED", "LAST-MODIFIED", "SEQUENCE":
+		case "SUMMARY":
+			e.Title = strings.TrimSpace(unescapeText(p.value))
+		case "DTSTART":
+			t, allDay, err := d.parseTime(uid, p)
+			if err != nil {
+				d.warnf("UID=%s: событие пропущено: неверный DTSTART %q", uid, p.value)
+				return nil
+			}
+			e.StartAt, e.AllDay = t, allDay
+		case "DTEND":
+			t, _, err := d.parseTime(uid, p)
+			if err != nil {
+				d.warnf("UID=%s: неверный DTEND %q проигнорирован", uid, p.value)
+				continue
+			}
+			end = t
+		case "DURATION":
+			v, err := parseDuration(p.value)
+			if err != nil {
+				d.warnf("UID=%s: неверный DURATION %q проигнорирован", uid, p.value)
+				continue
+			}
+			duration = v
+		case "PRIORITY":
+			e.Priority = parsePriority(p.value)
+		case "RRULE":
+			r, err := events.ParseRecurrence(p.value)
+			if err != nil {
+				d.warnf("UID=%s: событие пропущено: %v", uid, err)
+				return nil
+			}
+			e.Recurrence = r
+		case "EXDATE":
+			exdates = append(exdates, p)
+		case calendarProp:
+			cal := events.Calendar{Name: events.NormalizeCalendarName(unescapeText(p.value))}
+			if err := cal.Validate(); err != nil {
+				d.warnf("UID=%s: календарь проигнорирован: %v", uid, err)
+				continue
+			}
+			e.Calendar = cal.Name
+		default:
+			d.warnf("UID=%s: свойство %s не поддерживается", uid, p.name)
+		}
+	}
+
+	if e.StartAt.IsZero() {
+		d.warnf("UID=%s: событие пропущено: нет DTSTART", uid)
+		return nil
+	}
+	if end.IsZero() && duration > 0 {
+		end = e.StartAt.Add(duration)
+	}
+	if e.AllDay && end.Equal(e.StartAt.AddDate(0, 0, 1)) {
+		end = time.Time{}
+	}
+	e.StartAt = datetime.NormalizeUTCSeconds(e.StartAt)
+	if !end.IsZero() {
+		e.EndAt = datetime.NormalizeUTCSeconds(end)
+	}
+
+	if err := e.Validate(); err != nil {
+		d.warnf("UID=%s: событие пропущено: %v", uid, err)
+		return nil
+	}
+
+	for _, p := range exdates {
+		for _, v := range strings.Split(p.value, ",") {
+			t, _, err := d.parseTime(uid, property{name: p.name, params: p.params, value: v})
+			if err != nil {
+				d.warnf("UID=%s: неверный EXDATE %q проигнорирован", uid, v)
+				continue
+			}
+			ex := events.Exception{RecurrenceID: datetime.NormalizeUTCSeconds(t), Skip: true}
+			if err := e.AddException(ex); err != nil {
+				d.warnf("UID=%s: EXDATE %s проигнорирован: %v", uid, v, err)
+			}
+		}
+	}
+
+	for _, child := range c.children {
+		if child.name != "VALARM" {
+			d.warnf("UID=%s: компонент %s не поддерживается", uid, child.name)
+			continue
+		}
+		d.alarm(e, child)
+	}
+	return e
+}
+
+func (d *decoder) alarm(e *events.Event, c *component) {
+	message := ""
+	var trigger property
+	hasTrigger := false
+
+	for _, p := range c.props {
+		switch p.name {
+		case "ACTION":
+		case "DESCRIPTION":
+			message = strings.TrimSpace(unescapeText(p.value))
+		case "TRIGGER":
+			trigger = p
+			hasTrigger = true
+		default:
+			d.warnf("UID=%s: VALARM: свойство %s не поддерживается", e.ID, p.name)
+		}
+	}
+	if !hasTrigger {
+		d.warnf("UID=%s: VALARM без TRIGGER пропущен", e.ID)
+		return
+	}
+	if message == "" {
+		message = e.Title
+	}
+	if message == "" {
+		message = defaultMessage
+	}
+
+	var at time.Time
+	var offset time.Duration
+	if strings.ToUpper(trigger.params["VALUE"]) == "DATE-TIME" {
+		t, err := time.Parse(utcLayout, strings.TrimSpace(trigger.value))
+		if err != nil {
+			d.warnf("UID=%s: VALARM с неверным TRIGGER %q пропущен", e.ID, trigger.value)
+			return
+		}
+		at = t
+	} else {
+		if strings.ToUpper(trigger.params["RELATED"]) == "END" {
+			d.warnf("UID=%s: VALARM с TRIGGER относительно окончания не поддерживается", e.ID)
+			return
+		}
+		v, err := parseDuration(trigger.value)
+		if err != nil {
+			d.warnf("UID=%s: VALARM с неверным TRIGGER %q пропущен", e.ID, trigger.value)
+			return
+		}
+		if v > 0 {
+			d.warnf("UID=%s: VALARM после начала события не поддерживается", e.ID)
+			return
+		}
+		offset = -v
+		at = e.StartAt
+	}
+
+	if _, err := e.RestoreReminder(message, at, offset); err != nil {
+		d.warnf("UID=%s: VALARM пропущен: %v", e.ID, err)
+	}
+}
+
+func (d *decoder) override(c *component) {
+	uid := d.uid(c)
+	master, ok := d.masters[uid]
+	if !ok {
+		d.warnf("UID=%s: изменённое вхождение без основного события пропущено", uid)
+		return
+	}
+
+	rid, _ := c.prop("RECURRENCE-ID")
+	recurrenceID, _, err := d.parseTime(uid, rid)
+	if err != nil {
+		d.warnf("UID=%s: неверный RECURRENCE-ID %q", uid, rid.value)
+		return
+	}
+	ex := events.Exception{RecurrenceID: datetime.NormalizeUTCSeconds(recurrenceID)}
+
+	for _, p := range c.props {
+		switch p.name {
+		case "UID", "DTSTAMP", "CREATED", "LAST-MODIFIED", "SEQUENCE", "RECURRENCE-ID", "DTEND", "DURATION":
+		case "SUMMARY":
+			if title := strings.TrimSpace(unescapeText(p.value)); title != master.Title {
+				ex.Title = title
+			}
+		case "DTSTART":
+			t, _, err := d.parseTime(uid, p)
+			if err != nil {
+				d.warnf("UID=%s: неверный DTSTART %q вхождения", uid, p.value)
+				return
+			}
+			if t = datetime.NormalizeUTCSeconds(t); !t.Equal(ex.RecurrenceID) {
+				ex.StartAt = t
+			}
+		case "PRIORITY":
+			if parsePriority(p.value) != master.Priority {
+				d.warnf("UID=%s: приоритет отдельного вхождения не поддерживается", uid)
+			}
+		default:
+			d.warnf("UID=%s: свойство %s вхождения не поддерживается", uid, p.name)
+		}
+	}
+
+	if err := master.AddException(ex); err != nil {
+		d.warnf("UID=%s: вхождение %s пропущено: %v", uid, datetime.FormatLocal(ex.RecurrenceID), err)
+	}
+}
@@ -0,0 +1,99 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDuration разбирает значение DURATION из RFC 5545: "-PT15M", "P1DT2H", "P2W".
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = -1
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("DURATION=%q: %w", s, ErrInvalidFormat)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, ch := range s {
+		switch {
+		case ch >= '0' && ch <= '9':
+			num += string(ch)
+			continue
+		case ch == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("DURATION=%q: %w", s, ErrInvalidFormat)
+		}
+		num = ""
+
+		switch {
+		case ch == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case ch == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case ch == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case ch == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case ch == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("DURATION=%q: %w", s, ErrInvalidFormat)
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("DURATION=%q: %w", s, ErrInvalidFormat)
+	}
+	return sign * d, nil
+}
+
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		b.WriteString(strconv.Itoa(int(days)) + "D")
+	}
+	if d == 0 {
+		if days == 0 {
+			b.WriteString("T0S")
+		}
+		return b.String()
+	}
+
+	b.WriteByte('T')
+	if h := d / time.Hour; h > 0 {
+		b.WriteString(strconv.Itoa(int(h)) + "H")
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		b.WriteString(strconv.Itoa(int(m)) + "M")
+		d -= m * time.Minute
+	}
+	if s := d / time.Second; s > 0 {
+		b.WriteString(strconv.Itoa(int(s)) + "S")
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (enc *encoder) line(name string, value string) {
+	if enc.err != nil {
+		return
+	}
+	enc.err = writeFolded(enc.w, name+":"+value)
+}
+
+// writeFolded переносит строки длиннее 75 октетов, не разрывая UTF-8 последовательности.
+func writeFolded(w *bufio.Writer, s string) error {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8Start(s[cut]) {
+			cut--
+		}
+		if _, err := w.WriteString(s[:cut] + "\r\n "); err != nil {
+			return err
+		}
+		s = s[cut:]
+		limit = maxLineOctets - 1
+	}
+	_, err := w.WriteString(s + "\r\n")
+	return err
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func priorityValue(p events.Priority) string {
+	switch p {
+	case events.PriorityHigh:
+		return "1"
+	case events.PriorityLow:
+		return "9"
+	default:
+		return "5"
+	}
+}
+
+func Export(w io.Writer, list []*events.Event) error {
+	sorted := make([]*events.Event, len(list))
+	copy(sorted, list)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartAt.Before(sorted[j].StartAt) })
+
+	enc := &encoder{w: bufio.NewWriter(w)}
+	stamp := formatUTC(time.Now())
+
+	enc.line("BEGIN", "VCALENDAR")
+	enc.line("VERSION", "2.0")
+	enc.line("PRODID", prodID)
+	enc.line("CALSCALE", "GREGORIAN")
+	for _, e := range sorted {
+		enc.event(e, stamp)
+	}
+	enc.line("END", "VCALENDAR")
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return enc.w.Flush()
+}
+
+func (enc *encoder) event(e *events.Event, stamp string) {
+	enc.line("BEGIN", "VEVENT")
+	enc.line("UID", escapeText(e.ID))
+	enc.line("DTSTAMP", stamp)
+	enc.line("SUMMARY", escapeText(e.Title))
+	enc.times(e.AllDay, e.StartAt, e.EndAt, e.EndTime())
+	enc.line("PRIORITY", priorityValue(e.Priority))
+	enc.line(calendarProp, escapeText(e.CalendarName()))
+
+	if e.Recurrence != nil {
+		enc.line("RRULE", e.Recurrence.String())
+		var skipped []string
+		for _, ex := range e.Exceptions {
+			if ex.Skip {
+				skipped = append(skipped, enc.timeValue(e.AllDay, ex.RecurrenceID))
+			}
+		}
+		if len(skipped) > 0 {
+			name := "EXDATE"
+			if e.AllDay {
+				name += ";VALUE=DATE"
+			}
+			enc.line(name, strings.Join(skipped, ","))
+		}
+	}
+
+	for _, r := range e.Reminders {
+		enc.line("BEGIN", "VALARM")
+		enc.line("ACTION", "DISPLAY")
+		enc.line("DESCRIPTION", escapeText(r.Message))
+		if r.Offset > 0 {
+			enc.line("TRIGGER", formatDuration(-r.Offset))
+		} else {
+			enc.line("TRIGGER;VALUE=DATE-TIME", formatUTC(r.At))
+		}
+		enc.line("END", "VALARM")
+	}
+	enc.line("END", "VEVENT")
+
+	for _, ex := range e.Exceptions {
+		if ex.Skip {
+			continue
+		}
+		enc.override(e, ex, stamp)
+	}
+}
+
+func (enc *encoder) override(e *events.Event, ex events.Exception, stamp string) {
+	title := e.Title
+	if ex.Title != "" {
+		title = ex.Title
+	}
+	start := ex.RecurrenceID
+	if !ex.StartAt.IsZero() {
+		start = ex.StartAt
+	}
+	var end time.Time
+	if !e.EndAt.IsZero() || e.AllDay {
+		end = start.Add(e.Duration())
+	}
+
+	enc.line("BEGIN", "VEVENT")
+	enc.line("UID", escapeText(e.ID))
+	enc.line("DTSTAMP", stamp)
+	name := "RECURRENCE-ID"
+	if e.AllDay {
+		name += ";VALUE=DATE"
+	}
+	enc.line(name, enc.timeValue(e.AllDay, ex.RecurrenceID))
+	enc.line("SUMMARY", escapeText(title))
+	enc.times(e.AllDay, start, end, end)
+	enc.line("PRIORITY", priorityValue(e.Priority))
+	enc.line("END", "VEVENT")
+}
+
+func (enc *encoder) times(allDay bool, start time.Time, end time.Time, effectiveEnd time.Time) {
+	if allDay {
+		enc.line("DTSTART;VALUE=DATE", formatDate(start))
+		enc.line("DTEND;VALUE=DATE", formatDate(effectiveEnd))
+		return
+	}
+	enc.line("DTSTART", formatUTC(start))
+	if !end.IsZero() {
+		enc.line("DTEND", formatUTC(end))
+	}
+}
+
+func (enc *encoder) timeValue(allDay bool, t time.Time) string {
+	if allDay {
+		return formatDate(t)
+	}
+	return formatUTC(t)
+}
@@ -0,0 +1,61 @@
+package ical
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+const (
+	prodID         = "-//leksusdev//calendarOfEvents//RU"
+	utcLayout      = "20060102T150405Z"
+	localLayout    = "20060102T150405"
+	dateLayout     = "20060102"
+	maxLineOctets  = 75
+	defaultMessage = "Напоминание"
+	// calendarProp хранит имя календаря события, чтобы импорт вернул событие в тот же календарь.
+	calendarProp = "X-CALENDAROFEVENTS-CALENDAR"
+)
+
+var (
+	ErrInvalidFormat = errors.New("неверный формат iCalendar")
+	ErrNoCalendar    = errors.New("в файле нет VCALENDAR")
+)
+
+type Result struct {
+	Events   []*events.Event
+	Warnings []string
+}
+
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func formatUTC(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
+
+func formatDate(t time.Time) string {
+	return t.In(time.Local).Format(dateLayout)
+}
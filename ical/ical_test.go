@@ -0,0 +1,114 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	start := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+	e := &events.Event{
+		ID:       "uid-1",
+		Title:    "Встреча, важная",
+		StartAt:  start,
+		EndAt:    start.Add(time.Hour),
+		Priority: events.PriorityHigh,
+		Calendar: "work",
+	}
+	if _, err := e.RestoreReminder("Скоро; встреча", start, 15*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, []*events.Event{e}); err != nil {
+		t.Fatalf("Не ожидали ошибку экспорта, получили: %v", err)
+	}
+
+	res, err := Import(&buf)
+	if err != nil {
+		t.Fatalf("Не ожидали ошибку импорта, получили: %v", err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("Не ожидали предупреждений, получили: %v", res.Warnings)
+	}
+	if len(res.Events) != 1 {
+		t.Fatalf("Ожидали одно событие, получили %d", len(res.Events))
+	}
+
+	got := res.Events[0]
+	if got.ID != e.ID || got.Title != e.Title || !got.StartAt.Equal(e.StartAt) || !got.EndAt.Equal(e.EndAt) || got.Priority != e.Priority {
+		t.Errorf("Событие изменилось после экспорта и импорта: %+v", got)
+	}
+	if got.Calendar != "work" {
+		t.Errorf("Ожидали календарь work, получили %q", got.Calendar)
+	}
+	if len(got.Reminders) != 1 || got.Reminders[0].Offset != 15*time.Minute || got.Reminders[0].Message != "Скоро; встреча" {
+		t.Errorf("Напоминание изменилось после экспорта и импорта: %+v", got.Reminders)
+	}
+}
+
+func TestImportReportsUnsupported(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"X-WR-CALNAME:Work",
+		"BEGIN:VEVENT",
+		"UID:abc@example.com",
+		"SUMMARY:Созвон",
+		"DESCRIPTION:Длинное",
+		"  описание",
+		"DTSTART;TZID=Europe/Moscow:20300107T100000",
+		"DURATION:PT30M",
+		"LOCATION:Офис",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	res, err := Import(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Не ожидали ошибку, получили: %v", err)
+	}
+	if len(res.Events) != 1 {
+		t.Fatalf("Ожидали одно событие, получили %d", len(res.Events))
+	}
+	if d := res.Events[0].Duration(); d != 30*time.Minute {
+		t.Errorf("Ожидали длительность 30m, получили %v", d)
+	}
+	if want := time.Date(2030, 1, 7, 7, 0, 0, 0, time.UTC); !res.Events[0].StartAt.Equal(want) {
+		t.Errorf("Ожидали начало %v, получили %v", want, res.Events[0].StartAt)
+	}
+
+	joined := strings.Join(res.Warnings, "\n")
+	for _, want := range []string{"X-WR-CALNAME", "DESCRIPTION", "LOCATION", "VTODO"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Ожидали предупреждение о %s, получили: %v", want, res.Warnings)
+		}
+	}
+}
+
+func TestWriteFoldedKeepsRunes(t *testing.T) {
+	var buf bytes.Buffer
+	e := &events.Event{ID: strings.Repeat("я", 60), Title: "Тест", StartAt: time.Now(), Priority: events.PriorityLow}
+	if err := Export(&buf, []*events.Event{e}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("Строка длиннее %d октетов: %q", maxLineOctets, line)
+		}
+	}
+
+	res, err := Import(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Events) != 1 || res.Events[0].ID != e.ID {
+		t.Errorf("UID изменился после переноса строк")
+	}
+}
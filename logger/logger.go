@@ -18,10 +18,11 @@ func Init(filename string) error {
 		return err
 	}
 
-	logFile, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
+	logFile = f
 
 	infoLogger = log.New(logFile, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
 	errorLogger = log.New(logFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
@@ -31,14 +32,22 @@ func Init(filename string) error {
 
 func Close() {
 	if logFile != nil {
+		logFile.Sync()
 		logFile.Close()
+		logFile = nil
 	}
 }
 
 func Info(msg string) {
+	if infoLogger == nil {
+		return
+	}
 	infoLogger.Output(2, msg)
 }
 
 func Error(msg string) {
+	if errorLogger == nil {
+		return
+	}
 	errorLogger.Output(2, msg)
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/leksusdev/calendarOfEvents/calendar"
 	"github.com/leksusdev/calendarOfEvents/cmd"
@@ -11,22 +14,179 @@ import (
 )
 
 func main() {
+	script := flag.String("script", "", "выполнить команды из файла (- для stdin)")
+	continueOnError := flag.Bool("continue", false, "продолжать выполнение скрипта после ошибки")
+	encrypt := flag.Bool("encrypt", false, "шифровать данные паролем")
+	profile := flag.String("profile", "", "профиль с отдельными календарём, логами и настройками")
+	configFile := flag.String("config", "", "файл настроек (по умолчанию "+config.DefaultPath()+")")
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	err := config.SetProfile(*profile)
+	if err == nil {
+		err = config.Load(*configFile, flag.CommandLine)
+	}
+	if err == nil {
+		err = config.EnsureDirs()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка конфигурации: %v\n", err)
+		os.Exit(2)
+	}
+
 	if err := logger.Init(config.LogFileName); err != nil {
 		fmt.Printf("Ошибка инициализации логгера: %v\n", err)
-		return
+		os.Exit(1)
 	}
-	defer logger.Close()
 
-	logger.Info("Приложение запущено")
+	logger.Info(fmt.Sprintf("Приложение запущено, профиль %s", config.Profile))
+	if legacy, ok := config.LegacyData(); ok {
+		logger.Info("Найдены данные прежней версии: " + legacy)
+		fmt.Fprintf(os.Stderr, "Найдены данные прежней версии в %s; календарь теперь хранится в %s. "+
+			"Перенесите файлы или запустите с --data-dir %s\n", legacy, config.DataDir, config.LegacyDataDir)
+	}
 
-	s := storage.NewJsonStorage(config.DataFileName)
-	c := calendar.NewCalendar(s)
-	if err := c.Load(); err != nil {
+	args := flag.Args()
+	if *script == "" && len(args) == 0 && !isTerminal(os.Stdin) {
+		*script = "-"
+	}
+	batch := len(args) > 0 || *script != ""
+
+	var c *calendar.Calendar
+	s, encrypting, err := openStorage(config.StorageBackend, *encrypt)
+	if err == nil {
+		c = calendar.NewCalendar(s)
+		if batch {
+			c.DisableReminders()
+		}
+		err = c.Load()
+	}
+	if err == nil && encrypting {
+		err = c.Rewrite()
+	}
+	if err == nil {
+		err = encryptCopies(s)
+	}
+	if err == nil {
+		err = openJournal(c, s)
+	}
+	if err != nil {
 		logger.Error(fmt.Sprintf("Ошибка загрузки данных: %s", err))
-		return
+		code := 1
+		if batch {
+			code = cmd.WriteError(os.Stderr, fmt.Errorf("ошибка загрузки данных: %w", err))
+		} else {
+			fmt.Printf("Ошибка загрузки данных: %v\n", err)
+		}
+		closeStorage(s)
+		logger.Close()
+		os.Exit(code)
 	}
 
 	cli := cmd.NewCmd(c)
-	logger.Info("Запуск командной оболочки")
-	cli.Run()
+	if closer, ok := s.(io.Closer); ok {
+		cli.SetCloser(closer)
+	}
+	var code int
+	switch {
+	case *script != "":
+		code = cli.ExecScript(*script, *continueOnError)
+	case batch:
+		code = cli.Exec(args)
+	default:
+		logger.Info("Запуск командной оболочки")
+		code = cli.Run()
+	}
+	logger.Close()
+	os.Exit(code)
+}
+
+// openStorage открывает хранилище событий. Хранилища, которые сохраняют данные только
+// целиком, оборачиваются в storage.BlobRecords. Второе значение сообщает, что данные
+// ещё не зашифрованы и их нужно переписать зашифрованными, даже если календарь пуст:
+// иначе файл остался бы без заголовка шифрования и следующий запуск открыл бы его без пароля.
+func openStorage(backend string, encrypt bool) (storage.Store, bool, error) {
+	switch backend {
+	case config.StorageJSON:
+		s, encrypting, err := withEncryption(storage.NewJsonStorage(config.DataFileName), encrypt)
+		if err != nil {
+			return nil, false, err
+		}
+		return storage.NewBlobRecords(s), encrypting, nil
+	case config.StorageSQLite:
+		if encrypt {
+			return nil, false, fmt.Errorf("шифрование не поддерживается хранилищем %s", backend)
+		}
+		s, err := storage.NewSqliteStorage(config.SqliteFileName)
+		if err != nil {
+			return nil, false, err
+		}
+		return s, false, nil
+	default:
+		return nil, false, fmt.Errorf("неизвестное хранилище %q", backend)
+	}
+}
+
+// withEncryption оборачивает хранилище в storage.EncryptedStorage, если данные уже
+// зашифрованы или шифрование запрошено флагом; пароль для новых данных запрашивается дважды.
+func withEncryption(s storage.Store, encrypt bool) (storage.Store, bool, error) {
+	data, err := s.Load()
+	if err != nil {
+		return nil, false, err
+	}
+	encrypted := storage.IsEncrypted(data)
+	if !encrypted && !encrypt {
+		return s, false, nil
+	}
+
+	passphrase, err := cmd.AskPassphrase(!encrypted)
+	if err != nil {
+		return nil, false, err
+	}
+	es, err := storage.NewEncryptedStorage(s, passphrase)
+	if err != nil {
+		return nil, false, err
+	}
+	if !encrypted {
+		es.Migrate()
+	}
+	return es, !encrypted, nil
+}
+
+// encryptCopies шифрует резервные копии данных, оставшиеся открытым текстом с тех
+// пор, как календарь не был зашифрован.
+func encryptCopies(s storage.Store) error {
+	es, ok := storage.Base(s).(*storage.EncryptedStorage)
+	if !ok {
+		return nil
+	}
+	_, err := es.EncryptCopies()
+	return err
+}
+
+// openJournal подключает журнал изменений. У зашифрованного календаря журнал не
+// пишется на диск, чтобы не хранить события открытым текстом; undo и история
+// работают в пределах сессии. Журнал, оставшийся с тех пор, как календарь не был
+// зашифрован, удаляется.
+func openJournal(c *calendar.Calendar, s storage.Store) error {
+	if _, ok := storage.Base(s).(*storage.EncryptedStorage); ok {
+		logger.Info("Календарь зашифрован, журнал изменений хранится только в памяти")
+		return storage.NewJournal(config.JournalFileName).Remove()
+	}
+	return c.SetJournal(storage.NewJournal(config.JournalFileName))
+}
+
+func closeStorage(s storage.Store) {
+	closer, ok := s.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		logger.Error(fmt.Sprintf("Ошибка закрытия хранилища %s: %v", s.GetFilename(), err))
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
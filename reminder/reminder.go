@@ -1,18 +1,37 @@
 package reminder
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/leksusdev/calendarOfEvents/datetime"
 )
 
 type Reminder struct {
-	Message string       `json:"message"`
-	At      time.Time    `json:"at"`
-	Sent    bool         `json:"sent"`
-	Timer   *time.Timer  `json:"-"`
-	Notify  func(string) `json:"-"`
+	ID      string        `json:"id"`
+	Message string        `json:"message"`
+	At      time.Time     `json:"at"`
+	Offset  time.Duration `json:"offset,omitempty"`
+	Sent    bool          `json:"sent"`
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	gen     uint64
+	notify  func(string)
+	next    NextFunc
+	changed func()
+}
+
+type NextFunc func(prev time.Time) (time.Time, bool)
+
+type reminderJSON struct {
+	ID      string        `json:"id"`
+	Message string        `json:"message"`
+	At      time.Time     `json:"at"`
+	Offset  time.Duration `json:"offset,omitempty"`
+	Sent    bool          `json:"sent"`
 }
 
 func NewReminder(message string, at time.Time, notify func(string)) (*Reminder, error) {
@@ -31,30 +50,188 @@ func NewReminder(message string, at time.Time, notify func(string)) (*Reminder,
 		Message: msg,
 		At:      at,
 		Sent:    false,
-		Notify:  notify,
+		notify:  notify,
 	}, nil
 }
 
-func (r *Reminder) Start() {
-	if r.Timer != nil {
-		r.Timer.Stop()
+func Restore(message string, at time.Time) (*Reminder, error) {
+	msg, err := validateMessage(message)
+	if err != nil {
+		return nil, err
 	}
-	r.Timer = time.AfterFunc(time.Until(r.At), r.Send)
+
+	at = datetime.NormalizeUTCSeconds(at)
+	if at.IsZero() {
+		return nil, fmt.Errorf("ошибка проверки даты/времени: %w", ErrZeroTime)
+	}
+
+	return &Reminder{
+		Message: msg,
+		At:      at,
+		Sent:    !at.After(time.Now()),
+	}, nil
+}
+
+// Bind задаёт получателя уведомлений, расчёт следующего срабатывания для повторяющихся
+// событий и changed — вызов после того, как сработавшее напоминание отмечено и перенесено
+// на следующее повторение, чтобы владелец мог сохранить новое состояние.
+func (r *Reminder) Bind(notify func(string), next NextFunc, changed func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notify = notify
+	r.next = next
+	r.changed = changed
+}
+
+func (r *Reminder) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.startLocked()
+}
+
+func (r *Reminder) startLocked() {
+	r.stopLocked()
+	gen := r.gen
+	r.timer = time.AfterFunc(time.Until(r.At), func() { r.fire(gen, false) })
+}
+
+func (r *Reminder) StartMissed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopLocked()
+	gen := r.gen
+	r.timer = time.AfterFunc(0, func() { r.fire(gen, true) })
 }
 
 func (r *Reminder) Send() {
-	if r.Sent {
+	r.mu.Lock()
+	gen := r.gen
+	r.mu.Unlock()
+	r.fire(gen, false)
+}
+
+func (r *Reminder) SendMissed() {
+	r.mu.Lock()
+	gen := r.gen
+	r.mu.Unlock()
+	r.fire(gen, true)
+}
+
+// fire отправляет уведомление, если с момента запуска таймера напоминание
+// не было остановлено или перезапущено (gen не изменился). Напоминание повторяющегося
+// события сначала переносится на следующее повторение, и только потом вызывается
+// changed: сохранённое состояние не должно оставаться с отметкой Sent и прежним At.
+func (r *Reminder) fire(gen uint64, missed bool) {
+	r.mu.Lock()
+	if r.gen != gen || r.Sent {
+		r.mu.Unlock()
 		return
 	}
-	if r.Notify != nil {
-		r.Notify(fmt.Sprintf("Напоминание: \"%s\" - \"%s\"", r.Message, datetime.FormatLocal(r.At)))
+	r.Sent = true
+	format := "Напоминание: \"%s\" - \"%s\""
+	if missed {
+		format = "Пропущенное напоминание: \"%s\" - \"%s\""
 	}
+	msg := fmt.Sprintf(format, r.Message, datetime.FormatLocal(r.At))
+	notify, next, changed, prev := r.notify, r.next, r.changed, r.At
+	r.mu.Unlock()
+
+	r.repeat(gen, next, prev)
+	if changed != nil {
+		changed()
+	}
+	if notify != nil {
+		notify(msg)
+	}
+}
+
+func (r *Reminder) repeat(gen uint64, next NextFunc, prev time.Time) {
+	if next == nil {
+		return
+	}
+	now := time.Now()
+	at, ok := next(prev)
+	for ok && !at.After(now) {
+		at, ok = next(at)
+	}
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gen != gen {
+		return
+	}
+	r.At = datetime.NormalizeUTCSeconds(at)
+	r.Sent = false
+	r.startLocked()
+}
+
+func (r *Reminder) Skip() {
+	r.mu.Lock()
+	r.stopLocked()
 	r.Sent = true
+	gen, next, prev := r.gen, r.next, r.At
+	r.mu.Unlock()
+	r.repeat(gen, next, prev)
+}
+
+func (r *Reminder) Missed(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.Sent && !r.At.After(now)
+}
+
+// Reschedule переносит напоминание на at. Напоминание на прошедшее время не запускается:
+// неотправленное, оно становится пропущенным (Missed), и владелец обрабатывает его так же,
+// как пропущенные при загрузке.
+func (r *Reminder) Reschedule(at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopLocked()
+	r.At = datetime.NormalizeUTCSeconds(at)
+	if r.At.After(time.Now()) {
+		r.Sent = false
+		r.startLocked()
+	}
 }
 
 func (r *Reminder) Stop() {
-	if r.Timer != nil {
-		r.Timer.Stop()
-		r.Timer = nil
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopLocked()
+}
+
+func (r *Reminder) stopLocked() {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.gen++
+}
+
+func (r *Reminder) Snapshot() *Reminder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Reminder{
+		ID:      r.ID,
+		Message: r.Message,
+		At:      r.At,
+		Offset:  r.Offset,
+		Sent:    r.Sent,
+	}
+}
+
+func (r *Reminder) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	data := reminderJSON{
+		ID:      r.ID,
+		Message: r.Message,
+		At:      r.At,
+		Offset:  r.Offset,
+		Sent:    r.Sent,
 	}
+	r.mu.Unlock()
+	return json.Marshal(data)
 }
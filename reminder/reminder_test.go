@@ -0,0 +1,100 @@
+package reminder
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMissed(t *testing.T) {
+	now := time.Now()
+
+	r := &Reminder{Message: "Сообщение", At: now.Add(-time.Minute)}
+	if !r.Missed(now) {
+		t.Error("Ожидали true для неотправленного напоминания в прошлом, получили false")
+	}
+
+	r.Skip()
+	if r.Missed(now) || !r.Sent {
+		t.Error("Ожидали, что пропущенное напоминание будет помечено отправленным")
+	}
+
+	r = &Reminder{Message: "Сообщение", At: now.Add(time.Minute)}
+	if r.Missed(now) {
+		t.Error("Ожидали false для напоминания в будущем, получили true")
+	}
+}
+
+func TestRescheduleToPast(t *testing.T) {
+	r := &Reminder{Message: "Сообщение", At: time.Now().Add(time.Hour)}
+	r.Start()
+	r.Reschedule(time.Now().Add(-time.Minute))
+	if !r.Missed(time.Now()) {
+		t.Error("Ожидали пропущенное напоминание после переноса в прошлое")
+	}
+	if r.timer != nil {
+		t.Error("Не ожидали запущенного таймера после переноса в прошлое")
+	}
+}
+
+func TestSendMissed(t *testing.T) {
+	var got []string
+	r := &Reminder{
+		Message: "Сообщение",
+		At:      time.Now().Add(-time.Hour),
+		notify:  func(s string) { got = append(got, s) },
+	}
+
+	r.SendMissed()
+	r.SendMissed()
+
+	if len(got) != 1 {
+		t.Fatalf("Ожидали одно уведомление, получили %d", len(got))
+	}
+	if !r.Sent {
+		t.Error("Ожидали, что напоминание будет помечено отправленным")
+	}
+}
+
+func TestConcurrentFireAndStop(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	r := &Reminder{
+		Message: "Сообщение",
+		At:      time.Now().Add(time.Millisecond),
+		notify: func(string) {
+			mu.Lock()
+			count++
+			mu.Unlock()
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Go(func() {
+			for j := 0; j < 100; j++ {
+				switch j % 4 {
+				case 0:
+					r.Reschedule(time.Now().Add(time.Millisecond))
+				case 1:
+					r.Start()
+				case 2:
+					if _, err := json.Marshal(r); err != nil {
+						t.Error(err)
+					}
+				case 3:
+					r.Snapshot()
+				}
+			}
+		})
+	}
+	wg.Wait()
+	r.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if count > 400 {
+		t.Errorf("Слишком много уведомлений: %d", count)
+	}
+}
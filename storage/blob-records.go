@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+// BlobRecords — RecordStore поверх хранилища, которое умеет сохранять только весь
+// набор данных целиком (JsonStorage, ZipStorage, EncryptedStorage). Данные хранятся
+// в конверте (Envelope). Каждое изменение переписывает файл; прочитанный конверт
+// кэшируется, чтобы не разбирать (и не расшифровывать) файл при каждой записи.
+type BlobRecords struct {
+	mu    sync.Mutex
+	store Store
+	env   *Envelope
+}
+
+func NewBlobRecords(s Store) *BlobRecords {
+	return &BlobRecords{store: s}
+}
+
+// Unwrap возвращает хранилище, поверх которого работает BlobRecords.
+func (b *BlobRecords) Unwrap() Store {
+	return b.store
+}
+
+func (b *BlobRecords) GetFilename() string {
+	return b.store.GetFilename()
+}
+
+func (b *BlobRecords) Load() ([]byte, error) {
+	return b.store.Load()
+}
+
+// Save переписывает данные целиком в обход записей.
+func (b *BlobRecords) Save(data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.env = nil
+	return b.store.Save(data)
+}
+
+// Open читает данные перед началом работы. Повреждённый файл восстанавливается из
+// резервной копии (Recoverable), файл старой версии копируется рядом (Snapshotter) и
+// переписывается в текущем формате. Возвращает сообщения для пользователя.
+func (b *BlobRecords) Open() ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, err := b.store.Load()
+	if errors.Is(err, fs.ErrNotExist) {
+		b.env = NewEnvelope()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки из стораджа: %w", err)
+	}
+
+	env, from, err := decodeEvents(data)
+	var msg string
+	switch {
+	case errors.Is(err, ErrUnsupportedFormat), errors.Is(err, ErrMissingMigration):
+		return nil, fmt.Errorf("ошибка загрузки из %s: %w", b.store.GetFilename(), err)
+	case err != nil:
+		if env, msg, err = b.recoverFromBackup(err); err != nil {
+			return nil, err
+		}
+	case from < FormatVersion:
+		if msg, err = b.backupBeforeMigration(from); err != nil {
+			return nil, err
+		}
+	default:
+		b.env = env
+		return nil, nil
+	}
+
+	if err := b.save(env); err != nil {
+		return nil, err
+	}
+	return []string{msg}, nil
+}
+
+// decodeEvents разбирает файл данных любой поддерживаемой версии и проверяет, что
+// каждое событие читается; from — версия файла до миграции.
+func decodeEvents(data []byte) (*Envelope, int, error) {
+	env, from, err := DecodeEnvelope(data)
+	if err != nil {
+		return nil, from, err
+	}
+	for id, raw := range env.Events {
+		var e events.Event
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, from, fmt.Errorf("событие %s: %w", id, err)
+		}
+	}
+	return env, from, nil
+}
+
+// backupBeforeMigration сохраняет файл старой версии рядом с основным до того, как
+// Open перезапишет его в текущем формате.
+func (b *BlobRecords) backupBeforeMigration(from int) (string, error) {
+	name := b.store.GetFilename()
+	if sn, ok := b.store.(Snapshotter); ok {
+		suffix := fmt.Sprintf("v%d-%s.bak", from, time.Now().Format("20060102-150405"))
+		backup, err := sn.Snapshot(suffix)
+		if err != nil {
+			return "", fmt.Errorf("ошибка резервного копирования перед миграцией: %w", err)
+		}
+		name = backup
+	}
+
+	msg := fmt.Sprintf("Данные %s переведены с формата v%d на v%d, копия старого файла: %s",
+		b.store.GetFilename(), from, FormatVersion, name)
+	logger.Info(msg)
+	return msg, nil
+}
+
+// recoverFromBackup пытается прочитать последнюю удачно сохранённую версию данных, если основной
+// файл не удалось разобрать. Повреждённый файл хранилище оставляет рядом для разбора.
+func (b *BlobRecords) recoverFromBackup(parseErr error) (*Envelope, string, error) {
+	r, ok := b.store.(Recoverable)
+	if !ok {
+		return nil, "", fmt.Errorf("ошибка парсинга JSON: %w", parseErr)
+	}
+
+	data, err := r.Recover()
+	if err != nil {
+		return nil, "", fmt.Errorf("ошибка парсинга JSON: %w (восстановление не удалось: %v)", parseErr, err)
+	}
+
+	env, _, err := decodeEvents(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("ошибка парсинга резервной копии: %w", err)
+	}
+
+	msg := fmt.Sprintf("Файл %s повреждён (%v), данные восстановлены из резервной копии", b.store.GetFilename(), parseErr)
+	logger.Error(msg)
+	return env, msg, nil
+}
+
+// Rewrite переписывает данные целиком, даже если они не менялись: например, чтобы
+// зашифровать их новым паролем.
+func (b *BlobRecords) Rewrite() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	env, err := b.load()
+	if err != nil {
+		return err
+	}
+	return b.save(env)
+}
+
+// load возвращает кэшированный конверт, при необходимости читая его из хранилища.
+// Вызывающий должен держать b.mu.
+func (b *BlobRecords) load() (*Envelope, error) {
+	if b.env != nil {
+		return b.env, nil
+	}
+	data, err := b.store.Load()
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewEnvelope(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	env, _, err := DecodeEnvelope(data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+	b.env = env
+	return env, nil
+}
+
+// save записывает конверт и кэширует его. Вызывающий должен держать b.mu.
+func (b *BlobRecords) save(env *Envelope) error {
+	data, err := EncodeEnvelope(env)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации JSON: %w", err)
+	}
+	if err := b.store.Save(data); err != nil {
+		return err
+	}
+	b.env = env
+	return nil
+}
+
+func (b *BlobRecords) Records() ([]Record, error) {
+	return b.RecordsInRange(time.Time{}, time.Time{})
+}
+
+func (b *BlobRecords) RecordsInRange(from, to time.Time) ([]Record, error) {
+	b.mu.Lock()
+	env, err := b.load()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for id, data := range env.Events {
+		r, err := recordFromJSON(id, data)
+		if err != nil {
+			return nil, err
+		}
+		if inRange(r.StartAt, from, to) {
+			records = append(records, r)
+		}
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+func (b *BlobRecords) Record(id string) (Record, error) {
+	b.mu.Lock()
+	env, err := b.load()
+	b.mu.Unlock()
+	if err != nil {
+		return Record{}, err
+	}
+	data, ok := env.Events[id]
+	if !ok {
+		return Record{}, fmt.Errorf("id=%q: %w", id, ErrRecordNotFound)
+	}
+	return recordFromJSON(id, data)
+}
+
+func (b *BlobRecords) PutRecord(r Record) error {
+	return b.ApplyRecords([]Record{r}, nil)
+}
+
+func (b *BlobRecords) DeleteRecord(id string) error {
+	return b.ApplyRecords(nil, []string{id})
+}
+
+func (b *BlobRecords) Meta(key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	env, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	return env.Meta[key], nil
+}
+
+func (b *BlobRecords) PutMeta(key string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	env, err := b.load()
+	if err != nil {
+		return err
+	}
+	next := *env
+	next.Meta = maps.Clone(env.Meta)
+	if next.Meta == nil {
+		next.Meta = make(map[string]json.RawMessage)
+	}
+	next.Meta[key] = data
+	return b.save(&next)
+}
+
+// ApplyRecords записывает изменения в копию конверта: если сохранить её не удалось,
+// кэш остаётся прежним.
+func (b *BlobRecords) ApplyRecords(put []Record, deleted []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	env, err := b.load()
+	if err != nil {
+		return err
+	}
+	next := *env
+	next.Events = maps.Clone(env.Events)
+	for _, r := range put {
+		next.Events[r.ID] = r.Data
+	}
+	for _, id := range deleted {
+		delete(next.Events, id)
+	}
+	return b.save(&next)
+}
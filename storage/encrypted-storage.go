@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/leksusdev/calendarOfEvents/logger"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Формат зашифрованного файла: encryptedMagic, параметры scrypt (log2 N, r, p — по
+// байту), соль, nonce и шифротекст AES-256-GCM. Заголовок до nonce входит в
+// дополнительные данные GCM, так что подмена параметров или соли обнаруживается.
+var encryptedMagic = []byte("CALENC1\n")
+
+const (
+	scryptLogN   = 15
+	scryptR      = 8
+	scryptP      = 1
+	saltSize     = 16
+	keySize      = 32
+	headerSize   = 8 + 3 + saltSize
+	minEncrypted = headerSize + 12 + 16
+)
+
+var (
+	ErrWrongPassphrase = errors.New("неверный пароль или повреждённый файл")
+	ErrEmptyPassphrase = errors.New("пароль не может быть пустым")
+	ErrNotEncrypted    = errors.New("хранилище не зашифровано")
+	ErrPlaintextData   = errors.New("данные не зашифрованы")
+)
+
+// Rekeyer — хранилище, защищённое паролем.
+type Rekeyer interface {
+	CheckPassphrase(passphrase string) bool
+	SetPassphrase(passphrase string) error
+	// RekeyCopies перешифровывает текущим паролем копии данных, зашифрованные
+	// прежним паролем old. Возвращает имена перешифрованных файлов.
+	RekeyCopies(old string) ([]string, error)
+}
+
+// EncryptedStorage шифрует данные любого Store (JsonStorage, ZipStorage) ключом,
+// полученным из пароля через scrypt. Незашифрованные данные Load отвергает с
+// ErrPlaintextData, чтобы их нельзя было подложить вместо зашифрованного файла;
+// прочитать их можно только при переходе на шифрование (Migrate).
+type EncryptedStorage struct {
+	inner Store
+
+	mu         sync.Mutex
+	passphrase []byte
+	header     []byte
+	key        []byte
+	migrating  bool
+}
+
+func NewEncryptedStorage(inner Store, passphrase string) (*EncryptedStorage, error) {
+	s := &EncryptedStorage{inner: inner}
+	if err := s.SetPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// IsEncrypted сообщает, зашифрованы ли данные EncryptedStorage.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// Migrate разрешает читать данные открытым текстом до первого удачного сохранения:
+// так незашифрованный календарь переводится на шифрование.
+func (s *EncryptedStorage) Migrate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.migrating = true
+}
+
+func (s *EncryptedStorage) GetFilename() string {
+	return s.inner.GetFilename()
+}
+
+// SetPassphrase меняет пароль; данные перешифровываются при следующем Save.
+func (s *EncryptedStorage) SetPassphrase(passphrase string) error {
+	if passphrase == "" {
+		return ErrEmptyPassphrase
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("ошибка генерации соли: %w", err)
+	}
+	header := append(append([]byte{}, encryptedMagic...), scryptLogN, scryptR, scryptP)
+	header = append(header, salt...)
+	key, err := deriveKey([]byte(passphrase), header)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passphrase = []byte(passphrase)
+	s.header = header
+	s.key = key
+	return nil
+}
+
+func (s *EncryptedStorage) CheckPassphrase(passphrase string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return subtle.ConstantTimeCompare(s.passphrase, []byte(passphrase)) == 1
+}
+
+func deriveKey(passphrase, header []byte) ([]byte, error) {
+	logN, r, p := header[len(encryptedMagic)], header[len(encryptedMagic)+1], header[len(encryptedMagic)+2]
+	if logN == 0 || logN > 30 || r == 0 || p == 0 {
+		return nil, ErrWrongPassphrase
+	}
+	salt := header[len(encryptedMagic)+3 : headerSize]
+	key, err := scrypt.Key(passphrase, salt, 1<<logN, int(r), int(p), keySize)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ключа: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *EncryptedStorage) Save(data []byte) error {
+	out, err := s.seal(data)
+	if err != nil {
+		return err
+	}
+	if err := s.inner.Save(out); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.migrating = false
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *EncryptedStorage) seal(data []byte) ([]byte, error) {
+	s.mu.Lock()
+	header, key := s.header, s.key
+	s.mu.Unlock()
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка шифрования: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("ошибка генерации nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+// EncryptCopies шифрует копии данных, которые остались рядом с файлом открытым
+// текстом с тех пор, как хранилище не было зашифровано: резервную копию, копии перед
+// миграцией и повреждённые файлы. Возвращает имена зашифрованных файлов.
+func (s *EncryptedStorage) EncryptCopies() ([]string, error) {
+	return s.resealCopies(func(data []byte) ([]byte, bool) {
+		return data, !IsEncrypted(data)
+	})
+}
+
+// RekeyCopies перешифровывает копии данных после смены пароля: резервная копия,
+// которую Save оставляет от прежнего файла, зашифрована старым паролем, и без этого
+// восстановление из неё не удалось бы, а данные под старым паролем остались бы на диске.
+func (s *EncryptedStorage) RekeyCopies(old string) ([]string, error) {
+	return s.resealCopies(func(data []byte) ([]byte, bool) {
+		if !IsEncrypted(data) {
+			return data, true
+		}
+		plain, _, _, err := openSealed([]byte(old), data)
+		return plain, err == nil
+	})
+}
+
+// resealCopies шифрует текущим ключом копии данных, для которых plain возвращает
+// открытый текст и true.
+func (s *EncryptedStorage) resealCopies(plain func(data []byte) ([]byte, bool)) ([]string, error) {
+	cl, ok := s.inner.(CopyLister)
+	if !ok {
+		return nil, nil
+	}
+	names, err := cl.Copies()
+	if err != nil {
+		return nil, err
+	}
+
+	var sealed []string
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return sealed, fmt.Errorf("ошибка чтения %s: %w", name, err)
+		}
+		data, ok := plain(data)
+		if !ok {
+			continue
+		}
+		out, err := s.seal(data)
+		if err != nil {
+			return sealed, err
+		}
+		if err := writeFileAtomic(name, out, 0644); err != nil {
+			return sealed, fmt.Errorf("ошибка записи %s: %w", name, err)
+		}
+		logger.Info(fmt.Sprintf("Копия данных %s зашифрована", name))
+		sealed = append(sealed, name)
+	}
+	return sealed, nil
+}
+
+func (s *EncryptedStorage) Load() ([]byte, error) {
+	data, err := s.inner.Load()
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(data)
+}
+
+func (s *EncryptedStorage) decrypt(data []byte) ([]byte, error) {
+	s.mu.Lock()
+	passphrase, migrating := s.passphrase, s.migrating
+	s.mu.Unlock()
+	if !IsEncrypted(data) {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return data, nil
+		}
+		if !migrating {
+			logger.Error(fmt.Sprintf("Файл %s не зашифрован, хотя шифрование включено", s.GetFilename()))
+			return nil, fmt.Errorf("%s: %w", s.GetFilename(), ErrPlaintextData)
+		}
+		logger.Info(fmt.Sprintf("Файл %s не зашифрован, он будет зашифрован при сохранении", s.GetFilename()))
+		return data, nil
+	}
+	plain, header, key, err := openSealed(passphrase, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Дальше сохраняем с той же солью, чтобы не вычислять scrypt при каждом Save.
+	s.mu.Lock()
+	if bytes.Equal(s.passphrase, passphrase) {
+		s.header = append([]byte{}, header...)
+		s.key = key
+	}
+	s.mu.Unlock()
+	return plain, nil
+}
+
+// openSealed расшифровывает данные паролем passphrase и возвращает также заголовок и ключ,
+// чтобы последующие сохранения обходились без scrypt.
+func openSealed(passphrase, data []byte) ([]byte, []byte, []byte, error) {
+	if len(data) < minEncrypted {
+		return nil, nil, nil, ErrWrongPassphrase
+	}
+	header := data[:headerSize]
+	key, err := deriveKey(passphrase, header)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("ошибка расшифровки: %w", err)
+	}
+	nonce := data[headerSize : headerSize+gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, data[headerSize+gcm.NonceSize():], header)
+	if err != nil {
+		return nil, nil, nil, ErrWrongPassphrase
+	}
+	return plain, header, key, nil
+}
+
+func (s *EncryptedStorage) Recover() ([]byte, error) {
+	r, ok := s.inner.(Recoverable)
+	if !ok {
+		return nil, errors.New("хранилище не поддерживает восстановление")
+	}
+	data, err := r.Recover()
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(data)
+}
+
+func (s *EncryptedStorage) Snapshot(suffix string) (string, error) {
+	sn, ok := s.inner.(Snapshotter)
+	if !ok {
+		return "", errors.New("хранилище не поддерживает резервные копии")
+	}
+	return sn.Snapshot(suffix)
+}
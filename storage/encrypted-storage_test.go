@@ -0,0 +1,135 @@
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestEncryptedStorage(t *testing.T) {
+	dir := t.TempDir()
+	inner := map[string]func(string) storage.Store{
+		"calendar.json": func(name string) storage.Store { return storage.NewJsonStorage(name) },
+		"calendar.zip":  func(name string) storage.Store { return storage.NewZipStorage(name) },
+	}
+	plain := []byte(`{"format_version": 2, "events": {}}`)
+
+	for name, open := range inner {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			s, err := storage.NewEncryptedStorage(open(filename), "secret")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Save(plain); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := open(filename).Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !storage.IsEncrypted(raw) || bytes.Contains(raw, []byte("format_version")) {
+				t.Fatal("Не ожидали открытый текст в файле")
+			}
+
+			reopened, _ := storage.NewEncryptedStorage(open(filename), "secret")
+			if got, err := reopened.Load(); err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("Ожидали исходные данные, получили: %q, %v", got, err)
+			}
+			wrong, _ := storage.NewEncryptedStorage(open(filename), "guess")
+			if _, err := wrong.Load(); !errors.Is(err, storage.ErrWrongPassphrase) {
+				t.Errorf("Ожидали ErrWrongPassphrase для неверного пароля, получили: %v", err)
+			}
+
+			if !reopened.CheckPassphrase("secret") || reopened.CheckPassphrase("guess") {
+				t.Error("Ожидали, что CheckPassphrase примет только верный пароль")
+			}
+			if err := reopened.SetPassphrase("rotated"); err != nil {
+				t.Fatal(err)
+			}
+			if err := reopened.Save(plain); err != nil {
+				t.Fatal(err)
+			}
+			rekeyed, _ := storage.NewEncryptedStorage(open(filename), "rotated")
+			if got, err := rekeyed.Load(); err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("Ожидали исходные данные после смены пароля, получили: %q, %v", got, err)
+			}
+		})
+	}
+
+	// Незашифрованный файл читается только при переходе на шифрование (Migrate);
+	// после сохранения подложенный открытый текст снова отвергается.
+	filename := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(filename, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), "secret")
+	if _, err := s.Load(); !errors.Is(err, storage.ErrPlaintextData) {
+		t.Errorf("Ожидали ErrPlaintextData без Migrate, получили: %v", err)
+	}
+	s.Migrate()
+	if got, err := s.Load(); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Ожидали открытый текст при Migrate, получили: %q, %v", got, err)
+	}
+	if err := s.Save(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load(); !errors.Is(err, storage.ErrPlaintextData) {
+		t.Errorf("Ожидали ErrPlaintextData после сохранения, получили: %v", err)
+	}
+
+	if _, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), ""); !errors.Is(err, storage.ErrEmptyPassphrase) {
+		t.Errorf("Ожидали ErrEmptyPassphrase, получили: %v", err)
+	}
+}
+
+func TestEncryptCopies(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "calendar.json")
+	plain := []byte(`{"format_version": 4, "events": {}}`)
+	copies := []string{filename + ".bak", filename + ".v3-20300101-100000.bak", filename + ".corrupt-20300101-100000"}
+	others := []string{filename, filename + ".tmp-1", filepath.Join(dir, "calendars.json.bak")}
+	for _, name := range append(append([]string{}, copies...), others...) {
+		if err := os.WriteFile(name, plain, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.EncryptCopies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(copies) {
+		t.Errorf("Ожидали зашифрованные копии %v, получили: %v", copies, got)
+	}
+	for _, name := range copies {
+		if data, _ := os.ReadFile(name); !storage.IsEncrypted(data) {
+			t.Errorf("Не ожидали открытый текст в %s", filepath.Base(name))
+		}
+	}
+	for _, name := range others {
+		if data, _ := os.ReadFile(name); !bytes.Equal(data, plain) {
+			t.Errorf("Не ожидали изменений в %s", filepath.Base(name))
+		}
+	}
+	if again, err := s.EncryptCopies(); err != nil || len(again) != 0 {
+		t.Errorf("Не ожидали повторного шифрования копий, получили: %v, %v", again, err)
+	}
+
+	// Зашифрованная резервная копия по-прежнему годится для восстановления.
+	if data, err := s.Recover(); err != nil || !bytes.Equal(data, plain) {
+		t.Errorf("Ожидали восстановление из зашифрованной копии, получили: %q, %v", data, err)
+	}
+}
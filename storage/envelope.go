@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/config"
+)
+
+// FormatVersion — текущая версия формата файла данных. Версия 1 — голый JSON-объект
+// {"id": событие} без служебных полей.
+const FormatVersion = 5
+
+var (
+	ErrUnsupportedFormat = errors.New("версия формата данных новее поддерживаемой")
+	ErrMissingMigration  = errors.New("нет миграции формата данных")
+)
+
+// Envelope — файл данных календаря: версия формата, служебные отметки, события и
+// служебные данные календаря по ключу (MetaStore).
+type Envelope struct {
+	FormatVersion int                        `json:"format_version"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+	AppVersion    string                     `json:"app_version,omitempty"`
+	Events        map[string]json.RawMessage `json:"events"`
+	Meta          map[string]json.RawMessage `json:"meta,omitempty"`
+}
+
+func NewEnvelope() *Envelope {
+	now := time.Now().UTC()
+	return &Envelope{
+		FormatVersion: FormatVersion,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		AppVersion:    config.AppVersion,
+		Events:        make(map[string]json.RawMessage),
+	}
+}
+
+// Migration переводит данные из версии From в From+1.
+type Migration struct {
+	From        int
+	Description string
+	Up          func(data []byte) ([]byte, error)
+}
+
+var migrations = make(map[int]Migration)
+
+// RegisterMigration добавляет шаг миграции в реестр. Для каждой версии шаг один.
+func RegisterMigration(m Migration) {
+	if _, ok := migrations[m.From]; ok {
+		panic(fmt.Sprintf("миграция с версии %d уже зарегистрирована", m.From))
+	}
+	migrations[m.From] = m
+}
+
+func init() {
+	RegisterMigration(Migration{
+		From:        1,
+		Description: "события оборачиваются в конверт с версией формата",
+		Up:          wrapEnvelope,
+	})
+	RegisterMigration(Migration{
+		From:        2,
+		Description: "события в корзине хранятся вместе с остальными с полем deleted_at",
+		Up:          setVersion(3),
+	})
+	RegisterMigration(Migration{
+		From:        3,
+		Description: "события получают поле calendar с именем календаря",
+		Up:          setVersion(4),
+	})
+	RegisterMigration(Migration{
+		From:        4,
+		Description: "список календарей хранится в файле данных в поле meta",
+		Up:          setVersion(5),
+	})
+}
+
+// setVersion — миграция, которая меняет только версию формата: данные старой версии
+// уже подходят новой, но старое приложение не должно открывать новые файлы.
+func setVersion(version int) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		var env map[string]json.RawMessage
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, err
+		}
+		env["format_version"] = json.RawMessage(strconv.Itoa(version))
+		return json.Marshal(env)
+	}
+}
+
+func wrapEnvelope(data []byte) ([]byte, error) {
+	env := NewEnvelope()
+	if err := json.Unmarshal(data, &env.Events); err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// DataVersion определяет версию формата данных.
+func DataVersion(data []byte) (int, error) {
+	var head map[string]json.RawMessage
+	if err := json.Unmarshal(data, &head); err != nil {
+		return 0, err
+	}
+	raw, ok := head["format_version"]
+	if !ok {
+		return 1, nil
+	}
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return 0, fmt.Errorf("format_version: %w", err)
+	}
+	return version, nil
+}
+
+// Migrate по шагам переводит данные в FormatVersion и возвращает исходную версию.
+func Migrate(data []byte) ([]byte, int, error) {
+	from, err := DataVersion(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > FormatVersion {
+		return nil, from, fmt.Errorf("версия %d: %w", from, ErrUnsupportedFormat)
+	}
+
+	for v := from; v < FormatVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return nil, from, fmt.Errorf("с версии %d: %w", v, ErrMissingMigration)
+		}
+		if data, err = m.Up(data); err != nil {
+			return nil, from, fmt.Errorf("ошибка миграции с версии %d (%s): %w", v, m.Description, err)
+		}
+	}
+	return data, from, nil
+}
+
+// DecodeEnvelope разбирает файл данных любой поддерживаемой версии; from — версия
+// до миграции. Пустые данные дают пустой конверт.
+func DecodeEnvelope(data []byte) (*Envelope, int, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return NewEnvelope(), FormatVersion, nil
+	}
+
+	data, from, err := Migrate(data)
+	if err != nil {
+		return nil, from, err
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, from, err
+	}
+	if env.Events == nil {
+		env.Events = make(map[string]json.RawMessage)
+	}
+	return &env, from, nil
+}
+
+// EncodeEnvelope сериализует конверт текущей версии, обновляя отметку изменения.
+func EncodeEnvelope(env *Envelope) ([]byte, error) {
+	env.FormatVersion = FormatVersion
+	env.UpdatedAt = time.Now().UTC()
+	env.AppVersion = config.AppVersion
+	if env.CreatedAt.IsZero() {
+		env.CreatedAt = env.UpdatedAt
+	}
+	if config.PrettyJSON {
+		return json.MarshalIndent(env, "", "  ")
+	}
+	return json.Marshal(env)
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/leksusdev/calendarOfEvents/logger"
+)
+
+// Journal — файл, в который записи только дописываются, по одной JSON-строке.
+type Journal struct {
+	mu       sync.Mutex
+	filename string
+}
+
+func NewJournal(filename string) *Journal {
+	return &Journal{filename: filename}
+}
+
+func (j *Journal) GetFilename() string {
+	return j.filename
+}
+
+func (j *Journal) Append(line []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия журнала %s: %w", j.filename, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("ошибка записи в журнал %s: %w", j.filename, err)
+	}
+	return f.Close()
+}
+
+// Remove удаляет файл журнала; отсутствующий журнал ошибкой не считается.
+func (j *Journal) Remove() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := os.Remove(j.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка удаления журнала %s: %w", j.filename, err)
+	}
+	logger.Info(fmt.Sprintf("Журнал %s удалён", j.filename))
+	return nil
+}
+
+// Lines возвращает непустые строки журнала; отсутствующий журнал пуст.
+func (j *Journal) Lines() ([][]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	data, err := os.ReadFile(j.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения журнала %s: %w", j.filename, err)
+	}
+
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
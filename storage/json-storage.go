@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/leksusdev/calendarOfEvents/logger"
 )
@@ -20,7 +24,14 @@ func NewJsonStorage(filename string) *JsonStorage {
 
 func (s *JsonStorage) Save(data []byte) error {
 	filename := s.GetFilename()
-	err := os.WriteFile(filename, data, 0644)
+
+	if prev, err := os.ReadFile(filename); err == nil && len(prev) > 0 && !bytes.Equal(prev, data) {
+		if err := writeFileAtomic(s.backupFilename(), prev, 0644); err != nil {
+			logger.Error(fmt.Sprintf("Ошибка создания резервной копии %s: %v", s.backupFilename(), err))
+		}
+	}
+
+	err := writeFileAtomic(filename, data, 0644)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Ошибка сохранения в %s: %v", filename, err))
 		return err
@@ -29,6 +40,50 @@ func (s *JsonStorage) Save(data []byte) error {
 	return nil
 }
 
+func (s *JsonStorage) Recover() ([]byte, error) {
+	filename := s.GetFilename()
+	backup := s.backupFilename()
+
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Ошибка чтения резервной копии %s: %v", backup, err))
+		return nil, err
+	}
+
+	corrupt := fmt.Sprintf("%s.corrupt-%s", filename, time.Now().Format("20060102-150405"))
+	if err := os.Rename(filename, corrupt); err != nil && !os.IsNotExist(err) {
+		logger.Error(fmt.Sprintf("Ошибка переименования повреждённого файла %s: %v", filename, err))
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("Повреждённый файл %s сохранён как %s, данные восстановлены из %s", filename, corrupt, backup))
+	return data, nil
+}
+
+// Copies возвращает резервную копию, копии перед миграцией и повреждённые файлы,
+// лежащие рядом с файлом данных.
+func (s *JsonStorage) Copies() ([]string, error) {
+	filename := s.GetFilename()
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога %s: %w", filepath.Dir(filename), err)
+	}
+
+	base := filepath.Base(filename) + "."
+	var copies []string
+	for _, entry := range entries {
+		name := entry.Name()
+		suffix, ok := strings.CutPrefix(name, base)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if suffix == "bak" || strings.HasPrefix(suffix, "corrupt-") ||
+			(strings.HasPrefix(suffix, "v") && strings.HasSuffix(suffix, ".bak")) {
+			copies = append(copies, filepath.Join(filepath.Dir(filename), name))
+		}
+	}
+	return copies, nil
+}
+
 func (s *JsonStorage) Load() ([]byte, error) {
 	filename := s.GetFilename()
 
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/events"
+)
+
+var (
+	ErrRecordNotFound = errors.New("запись не найдена")
+	ErrEmptyID        = errors.New("у записи нет ID")
+)
+
+// Filter ограничивает List: события с началом в [From, To) и заданным приоритетом.
+// Нулевые поля не ограничивают выборку.
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Priority events.Priority
+}
+
+type Reader interface {
+	Get(id string) (*events.Event, error)
+	// List возвращает события, отсортированные по началу и ID.
+	List(f Filter) ([]*events.Event, error)
+}
+
+type Tx interface {
+	Reader
+	Put(e *events.Event) error
+	Delete(id string) error
+}
+
+// Repository — типизированное хранилище событий поверх любого RecordStore. Каждая
+// операция вне Update выполняется как отдельная транзакция.
+type Repository interface {
+	Tx
+	// Update выполняет fn в транзакции: внутри fn видны её собственные изменения,
+	// а в хранилище они попадают одной записью, только если fn вернула nil.
+	Update(fn func(tx Tx) error) error
+}
+
+type recordRepository struct {
+	mu    sync.Mutex
+	store RecordStore
+}
+
+func NewRepository(rs RecordStore) Repository {
+	return &recordRepository{store: rs}
+}
+
+func (r *recordRepository) Update(fn func(tx Tx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &recordTx{
+		store:   r.store,
+		put:     make(map[string]Record),
+		deleted: make(map[string]bool),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+func (r *recordRepository) Get(id string) (*events.Event, error) {
+	var e *events.Event
+	err := r.Update(func(tx Tx) error {
+		var err error
+		e, err = tx.Get(id)
+		return err
+	})
+	return e, err
+}
+
+func (r *recordRepository) List(f Filter) ([]*events.Event, error) {
+	var list []*events.Event
+	err := r.Update(func(tx Tx) error {
+		var err error
+		list, err = tx.List(f)
+		return err
+	})
+	return list, err
+}
+
+func (r *recordRepository) Put(e *events.Event) error {
+	return r.Update(func(tx Tx) error { return tx.Put(e) })
+}
+
+func (r *recordRepository) Delete(id string) error {
+	return r.Update(func(tx Tx) error { return tx.Delete(id) })
+}
+
+// recordTx накапливает изменения поверх хранилища до commit.
+type recordTx struct {
+	store   RecordStore
+	put     map[string]Record
+	deleted map[string]bool
+}
+
+func (tx *recordTx) Get(id string) (*events.Event, error) {
+	if tx.deleted[id] {
+		return nil, fmt.Errorf("id=%q: %w", id, ErrRecordNotFound)
+	}
+	if r, ok := tx.put[id]; ok {
+		return decodeRecord(r)
+	}
+	r, err := tx.store.Record(id)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecord(r)
+}
+
+func (tx *recordTx) List(f Filter) ([]*events.Event, error) {
+	records, err := tx.store.RecordsInRange(f.From, f.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*events.Event
+	add := func(r Record) error {
+		e, err := decodeRecord(r)
+		if err != nil {
+			return err
+		}
+		if f.Priority == "" || e.Priority == f.Priority {
+			list = append(list, e)
+		}
+		return nil
+	}
+	for _, r := range records {
+		if _, ok := tx.put[r.ID]; ok || tx.deleted[r.ID] {
+			continue
+		}
+		if err := add(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range tx.put {
+		if inRange(r.StartAt, f.From, f.To) {
+			if err := add(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].StartAt.Equal(list[j].StartAt) {
+			return list[i].StartAt.Before(list[j].StartAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (tx *recordTx) Put(e *events.Event) error {
+	if e.ID == "" {
+		return ErrEmptyID
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события %s: %w", e.ID, err)
+	}
+	tx.put[e.ID] = Record{ID: e.ID, StartAt: e.StartAt, Data: data}
+	delete(tx.deleted, e.ID)
+	return nil
+}
+
+func (tx *recordTx) Delete(id string) error {
+	if _, err := tx.Get(id); err != nil {
+		return err
+	}
+	delete(tx.put, id)
+	tx.deleted[id] = true
+	return nil
+}
+
+func (tx *recordTx) commit() error {
+	if len(tx.put) == 0 && len(tx.deleted) == 0 {
+		return nil
+	}
+	put := make([]Record, 0, len(tx.put))
+	for _, r := range tx.put {
+		put = append(put, r)
+	}
+	deleted := make([]string, 0, len(tx.deleted))
+	for id := range tx.deleted {
+		deleted = append(deleted, id)
+	}
+	return tx.store.ApplyRecords(put, deleted)
+}
+
+func decodeRecord(r Record) (*events.Event, error) {
+	var e events.Event
+	if err := json.Unmarshal(r.Data, &e); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга события %s: %w", r.ID, err)
+	}
+	return &e, nil
+}
+
+// recordFromJSON собирает запись из события в формате JSON, читая из него только
+// время начала.
+func recordFromJSON(id string, data []byte) (Record, error) {
+	var head struct {
+		StartAt time.Time `json:"start_at"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return Record{}, fmt.Errorf("ошибка парсинга события %s: %w", id, err)
+	}
+	return Record{ID: id, StartAt: head.StartAt, Data: data}, nil
+}
+
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].StartAt.Equal(records[j].StartAt) {
+			return records[i].StartAt.Before(records[j].StartAt)
+		}
+		return records[i].ID < records[j].ID
+	})
+}
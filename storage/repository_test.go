@@ -0,0 +1,41 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/leksusdev/calendarOfEvents/storage"
+	"github.com/leksusdev/calendarOfEvents/storage/storagetest"
+)
+
+func TestJsonRepository(t *testing.T) {
+	storagetest.TestRepository(t, "calendar.json", func(t *testing.T, filename string) storage.Repository {
+		return storage.NewRepository(storage.NewBlobRecords(storage.NewJsonStorage(filename)))
+	})
+}
+
+func TestZipRepository(t *testing.T) {
+	storagetest.TestRepository(t, "calendar.zip", func(t *testing.T, filename string) storage.Repository {
+		return storage.NewRepository(storage.NewBlobRecords(storage.NewZipStorage(filename)))
+	})
+}
+
+func TestSqliteRepository(t *testing.T) {
+	storagetest.TestRepository(t, "calendar.db", func(t *testing.T, filename string) storage.Repository {
+		s, err := storage.NewSqliteStorage(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return storage.NewRepository(s)
+	})
+}
+
+func TestEncryptedRepository(t *testing.T) {
+	storagetest.TestRepository(t, "calendar.json", func(t *testing.T, filename string) storage.Repository {
+		s, err := storage.NewEncryptedStorage(storage.NewJsonStorage(filename), "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return storage.NewRepository(storage.NewBlobRecords(s))
+	})
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/logger"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	id       TEXT PRIMARY KEY,
+	start_at INTEGER NOT NULL,
+	data     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_start_at ON events(start_at);
+CREATE TABLE IF NOT EXISTS meta (
+	key  TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+`
+
+// SqliteStorage хранит каждое событие отдельной строкой базы SQLite. Время начала
+// хранится в секундах Unix, чтобы выбирать события по диапазону через индекс.
+type SqliteStorage struct {
+	*Storage
+	db *sql.DB
+}
+
+func NewSqliteStorage(filename string) (*SqliteStorage, error) {
+	logger.Info(fmt.Sprintf("Инициализация SQLite хранилища: %s", filename))
+	dsn := "file:" + filename + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы %s: %w", filename, err)
+	}
+	// Одно соединение: SQLite всё равно сериализует запись, а так не бывает SQLITE_BUSY
+	// между соединениями одного процесса.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка создания схемы в %s: %w", filename, err)
+	}
+	return &SqliteStorage{
+		Storage: &Storage{filename: filename},
+		db:      db,
+	}, nil
+}
+
+func (s *SqliteStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *SqliteStorage) Records() ([]Record, error) {
+	return s.query(`SELECT id, start_at, data FROM events ORDER BY start_at, id`)
+}
+
+// RecordsInRange возвращает записи, время начала которых попадает в [from, to).
+// Нулевая граница не ограничивает диапазон.
+func (s *SqliteStorage) RecordsInRange(from, to time.Time) ([]Record, error) {
+	lo, hi := int64(-1<<63), int64(1<<63-1)
+	if !from.IsZero() {
+		lo = from.Unix()
+	}
+	if !to.IsZero() {
+		hi = to.Unix()
+	}
+	return s.query(`SELECT id, start_at, data FROM events WHERE start_at >= ? AND start_at < ? ORDER BY start_at, id`, lo, hi)
+}
+
+func (s *SqliteStorage) query(q string, args ...any) ([]Record, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		var start int64
+		var data string
+		if err := rows.Scan(&r.ID, &start, &data); err != nil {
+			return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+		}
+		r.StartAt = time.Unix(start, 0).UTC()
+		r.Data = []byte(data)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+	}
+	return records, nil
+}
+
+func (s *SqliteStorage) Record(id string) (Record, error) {
+	records, err := s.query(`SELECT id, start_at, data FROM events WHERE id = ?`, id)
+	if err != nil {
+		return Record{}, err
+	}
+	if len(records) == 0 {
+		return Record{}, fmt.Errorf("id=%q: %w", id, ErrRecordNotFound)
+	}
+	return records[0], nil
+}
+
+func (s *SqliteStorage) Meta(key string) ([]byte, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM meta WHERE key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+	}
+	return []byte(data), nil
+}
+
+func (s *SqliteStorage) PutMeta(key string, data []byte) error {
+	_, err := s.db.Exec(`INSERT INTO meta (key, data) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET data = excluded.data`, key, string(data))
+	if err != nil {
+		logger.Error(fmt.Sprintf("Ошибка сохранения в %s: %v", s.GetFilename(), err))
+		return fmt.Errorf("ошибка записи %s в %s: %w", key, s.GetFilename(), err)
+	}
+	logger.Info(fmt.Sprintf("Сохранено в %s: %s", s.GetFilename(), key))
+	return nil
+}
+
+func (s *SqliteStorage) PutRecord(r Record) error {
+	return s.ApplyRecords([]Record{r}, nil)
+}
+
+func (s *SqliteStorage) DeleteRecord(id string) error {
+	return s.ApplyRecords(nil, []string{id})
+}
+
+func (s *SqliteStorage) ApplyRecords(put []Record, deleted []string) error {
+	err := s.inTx(func(tx *sql.Tx) error {
+		return applyRecords(tx, put, deleted)
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Ошибка сохранения в %s: %v", s.GetFilename(), err))
+		return err
+	}
+	logger.Info(fmt.Sprintf("Сохранено в %s: изменено %d, удалено %d", s.GetFilename(), len(put), len(deleted)))
+	return nil
+}
+
+func applyRecords(tx *sql.Tx, put []Record, deleted []string) error {
+	for _, r := range put {
+		_, err := tx.Exec(`INSERT INTO events (id, start_at, data) VALUES (?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET start_at = excluded.start_at, data = excluded.data`,
+			r.ID, r.StartAt.Unix(), string(r.Data))
+		if err != nil {
+			return fmt.Errorf("ошибка записи события %s: %w", r.ID, err)
+		}
+	}
+	for _, id := range deleted {
+		if _, err := tx.Exec(`DELETE FROM events WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("ошибка удаления события %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (s *SqliteStorage) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Save заменяет все записи событиями и служебными данными из файла данных (Envelope любой поддерживаемой
+// версии). Нужен для совместимости со Store; календарь сохраняет изменения через
+// ApplyRecords.
+func (s *SqliteStorage) Save(data []byte) error {
+	env, _, err := DecodeEnvelope(data)
+	if err != nil {
+		return fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+
+	put := make([]Record, 0, len(env.Events))
+	for id, item := range env.Events {
+		r, err := recordFromJSON(id, item)
+		if err != nil {
+			return err
+		}
+		put = append(put, r)
+	}
+
+	err = s.inTx(func(tx *sql.Tx) error {
+		for _, table := range []string{"events", "meta"} {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return err
+			}
+		}
+		for key, data := range env.Meta {
+			if _, err := tx.Exec(`INSERT INTO meta (key, data) VALUES (?, ?)`, key, string(data)); err != nil {
+				return fmt.Errorf("ошибка записи %s: %w", key, err)
+			}
+		}
+		return applyRecords(tx, put, nil)
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Ошибка сохранения в %s: %v", s.GetFilename(), err))
+		return err
+	}
+	logger.Info(fmt.Sprintf("Данные успешно сохранены в %s", s.GetFilename()))
+	return nil
+}
+
+// Load собирает все записи и служебные данные в файл данных текущей версии (Envelope).
+func (s *SqliteStorage) Load() ([]byte, error) {
+	records, err := s.Records()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := s.allMeta()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 && len(meta) == 0 {
+		return nil, nil
+	}
+
+	env := NewEnvelope()
+	for _, r := range records {
+		if !json.Valid(r.Data) {
+			return nil, errors.New("повреждённая запись события " + r.ID)
+		}
+		env.Events[r.ID] = r.Data
+	}
+	if len(meta) > 0 {
+		env.Meta = meta
+	}
+	return EncodeEnvelope(env)
+}
+
+func (s *SqliteStorage) allMeta() (map[string]json.RawMessage, error) {
+	rows, err := s.db.Query(`SELECT key, data FROM meta`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+	}
+	defer rows.Close()
+
+	meta := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+		}
+		if !json.Valid([]byte(data)) {
+			return nil, errors.New("повреждённые служебные данные " + key)
+		}
+		meta[key] = json.RawMessage(data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения из %s: %w", s.GetFilename(), err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,66 @@
+package storage_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+func TestSqliteMetaInEnvelope(t *testing.T) {
+	dir := t.TempDir()
+	open := func(name string) *storage.SqliteStorage {
+		t.Helper()
+		s, err := storage.NewSqliteStorage(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	}
+
+	src := open("src.db")
+	if data, err := src.Meta("calendars"); err != nil || data != nil {
+		t.Fatalf("Ожидали пустые данные для отсутствующего ключа, получили: %q, %v", data, err)
+	}
+	list := []byte(`{"calendars":[{"name":"work"}]}`)
+	if err := src.PutMeta("calendars", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.PutMeta("calendars", list); err != nil {
+		t.Fatal(err)
+	}
+
+	// Save и Load переносят служебные данные вместе с событиями.
+	data, err := src.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env storage.Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	if compact(t, env.Meta["calendars"]) != string(list) {
+		t.Errorf("Ожидали %s в конверте, получили: %s", list, env.Meta["calendars"])
+	}
+
+	dst := open("dst.db")
+	if err := dst.Save(data); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dst.Meta("calendars"); err != nil || compact(t, got) != string(list) {
+		t.Errorf("Ожидали %s после Save, получили: %s, %v", list, got, err)
+	}
+}
+
+// compact убирает отступы, которые добавляет EncodeEnvelope при config.PrettyJSON.
+func compact(t *testing.T, data []byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
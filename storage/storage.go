@@ -1,11 +1,90 @@
 package storage
 
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
 type Store interface {
 	Save(data []byte) error
 	Load() ([]byte, error)
 	GetFilename() string
 }
 
+type Recoverable interface {
+	Recover() ([]byte, error)
+}
+
+// Record — одно событие в сериализованном виде. StartAt хранится отдельно, чтобы
+// хранилище могло выбирать записи по времени начала, не разбирая Data.
+type Record struct {
+	ID      string
+	StartAt time.Time
+	Data    []byte
+}
+
+// RecordStore — хранилище, которое сохраняет события по одному, а не переписывает
+// весь набор данных. Календарь работает через него с любым хранилищем: те, что
+// сохраняют данные только целиком, оборачиваются в BlobRecords.
+type RecordStore interface {
+	Records() ([]Record, error)
+	// Record возвращает запись по ID или ErrRecordNotFound.
+	Record(id string) (Record, error)
+	RecordsInRange(from, to time.Time) ([]Record, error)
+	PutRecord(r Record) error
+	DeleteRecord(id string) error
+	// ApplyRecords атомарно записывает put и удаляет deleted.
+	ApplyRecords(put []Record, deleted []string) error
+}
+
+// MetaStore хранит вместе с событиями служебные данные календаря по ключу, например
+// список календарей, чтобы они лежали в том же хранилище и так же шифровались.
+type MetaStore interface {
+	// Meta возвращает данные по ключу; для отсутствующего ключа — nil.
+	Meta(key string) ([]byte, error)
+	PutMeta(key string, data []byte) error
+}
+
+// Opener готовит данные хранилища к работе перед первым чтением, например
+// восстанавливает повреждённый файл или переводит его в текущий формат. Возвращает
+// сообщения для пользователя.
+type Opener interface {
+	Open() ([]string, error)
+}
+
+// Rewriter переписывает данные хранилища целиком, даже если они не менялись.
+type Rewriter interface {
+	Rewrite() error
+}
+
+// Unwrapper — обёртка, которая меняет способ записи, но не сами данные (BlobRecords).
+type Unwrapper interface {
+	Unwrap() Store
+}
+
+// Base возвращает хранилище под всеми обёртками Unwrapper.
+func Base(s Store) Store {
+	for {
+		u, ok := s.(Unwrapper)
+		if !ok {
+			return s
+		}
+		s = u.Unwrap()
+	}
+}
+
+// CopyLister перечисляет файлы с копиями данных хранилища (резервная копия, копии
+// перед миграцией, повреждённые файлы). Копии хранятся в том же виде, что и основной файл.
+type CopyLister interface {
+	Copies() ([]string, error)
+}
+
+// Snapshotter сохраняет копию текущего файла хранилища, например перед миграцией.
+type Snapshotter interface {
+	Snapshot(suffix string) (string, error)
+}
+
 type Storage struct {
 	filename string
 }
@@ -13,3 +92,20 @@ type Storage struct {
 func (s *Storage) GetFilename() string {
 	return s.filename
 }
+
+func (s *Storage) backupFilename() string {
+	return s.filename + ".bak"
+}
+
+// Snapshot копирует файл хранилища как есть в <файл>.<suffix> и возвращает имя копии.
+func (s *Storage) Snapshot(suffix string) (string, error) {
+	data, err := os.ReadFile(s.filename)
+	if err != nil {
+		return "", fmt.Errorf("ошибка чтения %s: %w", s.filename, err)
+	}
+	name := s.filename + "." + suffix
+	if err := writeFileAtomic(name, data, 0644); err != nil {
+		return "", fmt.Errorf("ошибка записи копии %s: %w", name, err)
+	}
+	return name, nil
+}
@@ -0,0 +1,194 @@
+// Package storagetest содержит общий набор проверок, который должна проходить
+// любая реализация storage.Repository.
+package storagetest
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leksusdev/calendarOfEvents/datetime"
+	"github.com/leksusdev/calendarOfEvents/events"
+	"github.com/leksusdev/calendarOfEvents/storage"
+)
+
+// Open открывает хранилище в файле filename; повторный вызов с тем же именем должен
+// видеть ранее сохранённые данные. Закрытие ресурсов Open регистрирует через t.Cleanup.
+type Open func(t *testing.T, filename string) storage.Repository
+
+// TestRepository проверяет реализацию storage.Repository; filename — имя файла
+// хранилища внутри временного каталога теста.
+func TestRepository(t *testing.T, filename string, open Open) {
+	newRepo := func(t *testing.T) (storage.Repository, string) {
+		path := filepath.Join(t.TempDir(), filename)
+		return open(t, path), path
+	}
+	base := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+
+	t.Run("GetMissing", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		if _, err := repo.Get("нет-такого"); !errors.Is(err, storage.ErrRecordNotFound) {
+			t.Errorf("Ожидали ErrRecordNotFound при чтении, получили: %v", err)
+		}
+		if err := repo.Delete("нет-такого"); !errors.Is(err, storage.ErrRecordNotFound) {
+			t.Errorf("Ожидали ErrRecordNotFound при удалении, получили: %v", err)
+		}
+	})
+
+	t.Run("PutGet", func(t *testing.T) {
+		repo, path := newRepo(t)
+		e := newEvent(t, "Встреча", base, events.PriorityHigh)
+		if _, err := e.AddReminder("Скоро встреча", "-15m", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Put(e); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := open(t, path).Get(e.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != e.Title || !got.StartAt.Equal(e.StartAt) || !got.EndAt.Equal(e.EndAt) ||
+			got.Priority != e.Priority || len(got.Reminders) != 1 {
+			t.Errorf("Ожидали событие %+v, получили: %+v", e, got)
+		}
+
+		e.Title = "Перенесённая встреча"
+		if err := repo.Put(e); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.Get(e.ID); err != nil || got.Title != e.Title {
+			t.Errorf("Ожидали событие после перезаписи, получили: %v, %v", got, err)
+		}
+
+		if err := repo.Put(&events.Event{Title: "Без ID"}); !errors.Is(err, storage.ErrEmptyID) {
+			t.Errorf("Ожидали ErrEmptyID для события без ID, получили: %v", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		var ids []string
+		for i, p := range []events.Priority{events.PriorityLow, events.PriorityHigh, events.PriorityHigh} {
+			// Добавляем в обратном порядке, чтобы проверить сортировку.
+			e := newEvent(t, "Встреча", base.AddDate(0, 0, 2-i), p)
+			if err := repo.Put(e); err != nil {
+				t.Fatal(err)
+			}
+			ids = append([]string{e.ID}, ids...)
+		}
+
+		all, err := repo.List(storage.Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventIDs(all); !equal(got, ids) {
+			t.Errorf("Ожидали события %v, получили: %v", ids, got)
+		}
+
+		ranged, err := repo.List(storage.Filter{From: base.Add(time.Hour), To: base.AddDate(0, 0, 2)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventIDs(ranged); !equal(got, ids[1:2]) {
+			t.Errorf("Ожидали события %v в диапазоне, получили: %v", ids[1:2], got)
+		}
+
+		high, err := repo.List(storage.Filter{Priority: events.PriorityHigh})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventIDs(high); !equal(got, ids[:2]) {
+			t.Errorf("Ожидали события %v с фильтром по приоритету, получили: %v", ids[:2], got)
+		}
+
+		if err := repo.Delete(ids[0]); err != nil {
+			t.Fatal(err)
+		}
+		if rest, _ := repo.List(storage.Filter{}); !equal(eventIDs(rest), ids[1:]) {
+			t.Errorf("Ожидали события %v после удаления, получили: %v", ids[1:], eventIDs(rest))
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo, path := newRepo(t)
+		kept := newEvent(t, "Встреча", base, events.PriorityLow)
+		if err := repo.Put(kept); err != nil {
+			t.Fatal(err)
+		}
+
+		added := newEvent(t, "Созвон", base.Add(2*time.Hour), events.PriorityMedium)
+		errAbort := errors.New("отмена")
+		err := repo.Update(func(tx storage.Tx) error {
+			if err := tx.Put(added); err != nil {
+				return err
+			}
+			if err := tx.Delete(kept.ID); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("Ожидали ошибку %v из транзакции, получили: %v", errAbort, err)
+		}
+		if list, _ := open(t, path).List(storage.Filter{}); !equal(eventIDs(list), []string{kept.ID}) {
+			t.Errorf("Ожидали только %s после отката, получили: %v", kept.ID, eventIDs(list))
+		}
+
+		err = repo.Update(func(tx storage.Tx) error {
+			if err := tx.Put(added); err != nil {
+				return err
+			}
+			if _, err := tx.Get(added.ID); err != nil {
+				return err
+			}
+			if list, err := tx.List(storage.Filter{}); err != nil || len(list) != 2 {
+				t.Errorf("Ожидали 2 события внутри транзакции, получили: %d, %v", len(list), err)
+			}
+			if err := tx.Delete(kept.ID); err != nil {
+				return err
+			}
+			if _, err := tx.Get(kept.ID); !errors.Is(err, storage.ErrRecordNotFound) {
+				t.Errorf("Ожидали ErrRecordNotFound для удаленного внутри транзакции события, получили: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list, _ := open(t, path).List(storage.Filter{}); !equal(eventIDs(list), []string{added.ID}) {
+			t.Errorf("Ожидали только %s после фиксации, получили: %v", added.ID, eventIDs(list))
+		}
+	})
+}
+
+func newEvent(t *testing.T, title string, start time.Time, p events.Priority) *events.Event {
+	t.Helper()
+	e, err := events.NewEvent(title, datetime.FormatLocal(start), "1h", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func eventIDs(list []*events.Event) []string {
+	ids := make([]string, 0, len(list))
+	for _, e := range list {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,10 +2,10 @@ package storage
 
 import (
 	"archive/zip"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/leksusdev/calendarOfEvents/config"
 )
@@ -21,24 +21,8 @@ func NewZipStorage(filename string) *ZipStorage {
 }
 
 func (z *ZipStorage) Save(data []byte) error {
-	f, err := os.Create(z.GetFilename())
-	if err != nil {
-		return fmt.Errorf("ошибка создания файла: %w", err)
-	}
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			fmt.Println(err)
-		}
-	}(f)
-
-	zw := zip.NewWriter(f)
-	defer func(zw *zip.Writer) {
-		err := zw.Close()
-		if err != nil {
-			fmt.Println(err)
-		}
-	}(zw)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
 
 	w, err := zw.Create(config.ZipLogEntryName)
 	if err != nil {
@@ -48,7 +32,13 @@ func (z *ZipStorage) Save(data []byte) error {
 	if err != nil {
 		return fmt.Errorf("ошибка записи данных в архив: %w", err)
 	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("ошибка записи данных в архив: %w", err)
+	}
 
+	if err := writeFileAtomic(z.GetFilename(), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("ошибка создания файла: %w", err)
+	}
 	return nil
 }
 